@@ -1,11 +1,19 @@
 package check
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/ljfranklin/terraform-resource/jsonplan"
 	"github.com/ljfranklin/terraform-resource/workspaces"
 
 	"github.com/ljfranklin/terraform-resource/models"
@@ -13,6 +21,9 @@ import (
 	"github.com/ljfranklin/terraform-resource/terraform"
 )
 
+// checkEnvWorkers bounds how many states are fetched at once with `check_env_pattern`
+const checkEnvWorkers = 4
+
 type Runner struct {
 	LogWriter io.Writer
 }
@@ -22,9 +33,15 @@ func (r Runner) Run(req models.InRequest) ([]models.Version, error) {
 		return []models.Version{}, err
 	}
 
+	if req.Version.IsReap() {
+		// a reap version does not belong to any env, so check as if there were no version yet
+		req.Version = models.Version{}
+	}
+
 	if req.Source.BackendType != "" && req.Source.MigratedFromStorage != (storage.Model{}) {
-		if req.Version.IsZero() && req.Source.EnvName == "" {
-			// Triggering on new versions is only supported in single-env mode:
+		if req.Version.IsZero() && req.Source.EnvName == "" && req.Source.CheckEnvPattern == "" {
+			// Triggering on new versions is only supported in single-env mode
+			// unless `check_env_pattern` opts in to checking multiple envs:
 			// - expensive to check for changes across all statefiles
 			// - triggering on changes to any environment doesn't seem very useful
 			return []models.Version{}, nil
@@ -48,8 +65,13 @@ func (r Runner) Run(req models.InRequest) ([]models.Version, error) {
 }
 
 func (r Runner) runWithBackend(req models.InRequest) ([]models.Version, error) {
+	if req.Source.CheckEnvPattern != "" {
+		return r.runWithEnvPattern(req)
+	}
+
 	if req.Version.IsZero() && req.Source.EnvName == "" {
-		// Triggering on new versions is only supported in single-env mode:
+		// Triggering on new versions is only supported in single-env mode
+		// unless `check_env_pattern` opts in to checking multiple envs:
 		// - expensive to check for changes across all statefiles
 		// - triggering on changes to any environment doesn't seem very useful
 		return []models.Version{}, nil
@@ -61,6 +83,18 @@ func (r Runner) runWithBackend(req models.InRequest) ([]models.Version, error) {
 		}
 	}
 
+	var targetEnvName string
+	if req.Source.EnvName != "" {
+		// `put` applies `env_name_rules`, so the env must be looked up by the normalized name
+		var err error
+		targetEnvName, err = req.Source.EnvNameRules.Normalize(req.Source.EnvName)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		targetEnvName = req.Version.EnvName
+	}
+
 	terraformModel := req.Source.Terraform
 	terraformModel.Source = "" // ensures that files are created in current dir
 	if err := terraformModel.Validate(); err != nil {
@@ -72,14 +106,21 @@ func (r Runner) runWithBackend(req models.InRequest) ([]models.Version, error) {
 		r.LogWriter,
 	)
 
-	workspaces := workspaces.New(client)
+	var driftDir string
+	if req.Source.DetectDrift {
+		var err error
+		driftDir, err = ioutil.TempDir(os.TempDir(), "terraform-resource-check")
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create tmp dir at '%s'", os.TempDir())
+		}
+		defer os.RemoveAll(driftDir)
 
-	var targetEnvName string
-	if req.Source.EnvName != "" {
-		targetEnvName = req.Source.EnvName
-	} else {
-		targetEnvName = req.Version.EnvName
+		if err = r.prepareDriftDetection(req, client, targetEnvName, driftDir); err != nil {
+			return nil, err
+		}
 	}
+
+	workspaces := workspaces.NewWithPrefix(client, req.Source.WorkspacePrefix)
 	latestVersion, err := workspaces.LatestVersionForEnv(targetEnvName)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to check backend for latest version of '%s': %s", targetEnvName, err)
@@ -97,17 +138,178 @@ func (r Runner) runWithBackend(req models.InRequest) ([]models.Version, error) {
 		}
 
 		if latestVersion.Serial >= serialFromVersion || latestVersion.Lineage != req.Version.Lineage {
-			resp = append(resp, models.Version{
+			version := models.Version{
 				EnvName: targetEnvName,
 				Serial:  strconv.Itoa(latestVersion.Serial),
 				Lineage: latestVersion.Lineage,
-			})
+			}
+
+			if req.Source.DetectDrift {
+				driftedAddresses, err := r.driftedAddresses(client, req.Source.Terraform.WorkspaceName(targetEnvName), driftDir)
+				if err != nil {
+					return nil, fmt.Errorf("Failed to detect drift for '%s': %s", targetEnvName, err)
+				}
+				if len(driftedAddresses) > 0 {
+					version.Drift = "true" // Concourse demands version fields are strings
+					version.DriftChecksum = driftChecksum(driftedAddresses)
+				}
+			}
+
+			resp = append(resp, version)
 		}
 	}
 
 	return resp, nil
 }
 
+func (r Runner) runWithEnvPattern(req models.InRequest) ([]models.Version, error) {
+	if req.Version.IsZero() == false {
+		if err := req.Version.Validate(); err != nil {
+			return nil, fmt.Errorf("Failed to validate provided version: %s", err)
+		}
+	}
+
+	pattern, err := regexp.Compile(req.Source.CheckEnvPattern)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid `check_env_pattern`: %s", err)
+	}
+
+	terraformModel := req.Source.Terraform
+	terraformModel.Source = "" // ensures that files are created in current dir
+	if err = terraformModel.Validate(); err != nil {
+		return nil, fmt.Errorf("Failed to validate terraform Model: %s", err)
+	}
+
+	client := terraform.NewClient(
+		terraformModel,
+		r.LogWriter,
+	)
+
+	latestVersions, err := workspaces.NewWithPrefix(client, req.Source.WorkspacePrefix).LatestVersionsMatching(pattern, checkEnvWorkers)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to check backend for envs matching '%s': %s", req.Source.CheckEnvPattern, err)
+	}
+
+	return EnvVersions(latestVersions, req.Version), nil
+}
+
+// EnvVersions returns the versions reported by `check_env_pattern`. Concourse
+// treats the last version as the newest, so the current version goes first
+// and is only followed by the envs which changed after it was emitted, as
+// recorded by its `checked_envs`. Every other version records the envs seen
+// by this check in turn, so an unchanged env is never reported again.
+func EnvVersions(latestVersions map[string]terraform.StateVersion, current models.Version) []models.Version {
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(current.CheckedEnvs, ",") {
+		seen[entry] = true
+	}
+
+	versions := []models.Version{}
+	if _, ok := latestVersions[current.EnvName]; ok {
+		versions = append(versions, current)
+	}
+
+	envNames := []string{}
+	for envName := range latestVersions {
+		envNames = append(envNames, envName)
+	}
+	sort.Strings(envNames)
+
+	snapshot := workspaces.Snapshot(latestVersions)
+	for _, envName := range envNames {
+		latestVersion := latestVersions[envName]
+		version := models.Version{
+			EnvName:     envName,
+			Serial:      strconv.Itoa(latestVersion.Serial),
+			Lineage:     latestVersion.Lineage,
+			CheckedEnvs: snapshot,
+		}
+		unchangedCurrentEnv := envName == current.EnvName && version.Serial == current.Serial && version.Lineage == current.Lineage
+		if unchangedCurrentEnv || seen[workspaces.SnapshotEntry(envName, latestVersion)] {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+func (r Runner) prepareDriftDetection(req models.InRequest, client terraform.Client, envName string, tmpDir string) error {
+	terraformModel := req.Source.Terraform
+
+	// check has no inputs, so the configuration is fetched from the module address
+	terraformModel.Source = path.Join(tmpDir, "source")
+	if err := os.Mkdir(terraformModel.Source, 0755); err != nil {
+		return err
+	}
+
+	env := map[string]string{}
+	for key, value := range terraformModel.Env {
+		env[key] = value
+	}
+	env["TF_VAR_env_name"] = envName
+	// build metadata is only available during `put`, but configs may still declare these vars
+	for _, buildVar := range []string{
+		"TF_VAR_build_id",
+		"TF_VAR_build_name",
+		"TF_VAR_build_job_name",
+		"TF_VAR_build_pipeline_name",
+		"TF_VAR_build_team_name",
+		"TF_VAR_atc_external_url",
+	} {
+		if _, ok := env[buildVar]; !ok {
+			env[buildVar] = ""
+		}
+	}
+	terraformModel.Env = env
+
+	terraformModel.PlanFileLocalPath = path.Join(tmpDir, "plan")
+	terraformModel.JSONPlanFileLocalPath = path.Join(tmpDir, "plan.json")
+	terraformModel.DownloadPlugins = true
+	client.SetModel(terraformModel)
+
+	if err := client.InitFromModule(req.Source.Terraform.Source); err != nil {
+		return fmt.Errorf("Failed to fetch `terraform_source` for drift detection: %s", err)
+	}
+
+	// var files are resolved relative to the fetched module
+	varFiles := []string{}
+	for _, varFile := range terraformModel.VarFiles {
+		varFiles = append(varFiles, path.Join(terraformModel.Source, varFile))
+	}
+	terraformModel.VarFiles = varFiles
+	if err := terraformModel.ConvertVarFiles(tmpDir); err != nil {
+		return fmt.Errorf("Failed to parse `terraform.var_files`: %s", err)
+	}
+	client.SetModel(terraformModel)
+
+	return nil
+}
+
+func (r Runner) driftedAddresses(client terraform.Client, workspace string, tmpDir string) ([]string, error) {
+	hasDrift, err := client.RefreshOnlyPlan(workspace)
+	if err != nil {
+		return nil, err
+	}
+	if !hasDrift {
+		return []string{}, nil
+	}
+
+	if err = client.JSONPlan(); err != nil {
+		return nil, err
+	}
+	plan, err := jsonplan.Read(path.Join(tmpDir, "plan.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.DriftedAddresses(), nil
+}
+
+func driftChecksum(addresses []string) string {
+	h := sha256.Sum256([]byte(strings.Join(addresses, "\n")))
+	return fmt.Sprintf("%x", h)
+}
+
 func (r Runner) runWithLegacyStorage(req models.InRequest) ([]models.Version, error) {
 	currentVersionTime := time.Time{}
 	if req.Version.IsZero() == false {
@@ -8,6 +8,8 @@ import (
 
 	"github.com/ljfranklin/terraform-resource/check"
 	"github.com/ljfranklin/terraform-resource/models"
+	"github.com/ljfranklin/terraform-resource/namer/namerfakes"
+	"github.com/ljfranklin/terraform-resource/out"
 	"github.com/ljfranklin/terraform-resource/test/helpers"
 
 	. "github.com/onsi/ginkgo"
@@ -24,25 +26,29 @@ var _ = Describe("Check with Terraform Backend", func() {
 		pathToPrevS3Fixture string
 		pathToCurrS3Fixture string
 		awsVerifier         *helpers.AWSVerifier
+		accessKey           string
+		secretKey           string
+		bucketPath          string
+		region              string
 		workingDir          string
 		workspacePath       string
 		expectedLineage     = "f62eee11-6a4e-4d39-b5c7-15d3dad8e5f7"
 	)
 
 	BeforeEach(func() {
-		accessKey := os.Getenv("AWS_ACCESS_KEY")
+		accessKey = os.Getenv("AWS_ACCESS_KEY")
 		Expect(accessKey).ToNot(BeEmpty(), "AWS_ACCESS_KEY must be set")
 
-		secretKey := os.Getenv("AWS_SECRET_KEY")
+		secretKey = os.Getenv("AWS_SECRET_KEY")
 		Expect(secretKey).ToNot(BeEmpty(), "AWS_SECRET_KEY must be set")
 
 		bucket = os.Getenv("AWS_BUCKET")
 		Expect(bucket).ToNot(BeEmpty(), "AWS_BUCKET must be set")
 
-		bucketPath := os.Getenv("AWS_BUCKET_SUBFOLDER")
+		bucketPath = os.Getenv("AWS_BUCKET_SUBFOLDER")
 		Expect(bucketPath).ToNot(BeEmpty(), "AWS_BUCKET_SUBFOLDER must be set")
 
-		region := os.Getenv("AWS_REGION") // optional
+		region = os.Getenv("AWS_REGION") // optional
 		if region == "" {
 			region = "us-east-1"
 		}
@@ -353,4 +359,93 @@ var _ = Describe("Check with Terraform Backend", func() {
 			Expect(resp).To(Equal(expectOutput))
 		})
 	})
+
+	Context("when `source.detect_drift` is set", func() {
+		var (
+			driftEnvName      string
+			s3ObjectPath      string
+			pathToDriftState  string
+			driftCheckVersion models.Version
+		)
+
+		BeforeEach(func() {
+			driftEnvName = helpers.RandomString("check-drift-test")
+			s3ObjectPath = path.Join(bucketPath, helpers.RandomString("check-drift-test"))
+			pathToDriftState = path.Join(workspacePath, driftEnvName, "terraform.tfstate")
+
+			vars := map[string]interface{}{
+				"access_key":     accessKey,
+				"secret_key":     secretKey,
+				"bucket":         bucket,
+				"object_key":     s3ObjectPath,
+				"object_content": "terraform-is-neat",
+				"region":         region,
+			}
+
+			runner := out.Runner{
+				SourceDir: workingDir,
+				LogWriter: GinkgoWriter,
+				Namer:     &namerfakes.FakeNamer{},
+			}
+			outResp, err := runner.Run(models.OutRequest{
+				Source: models.Source{
+					Terraform: checkInput.Source.Terraform,
+				},
+				Params: models.OutParams{
+					EnvName: driftEnvName,
+					Terraform: models.Terraform{
+						Source: "fixtures/aws/",
+						Vars:   vars,
+					},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			driftCheckVersion = outResp.Version
+
+			checkInput.Source.EnvName = driftEnvName
+			checkInput.Source.DetectDrift = true
+			checkInput.Source.Terraform.Source = path.Join(workingDir, "fixtures/aws/")
+			checkInput.Source.Terraform.Vars = vars
+		})
+
+		AfterEach(func() {
+			awsVerifier.DeleteObjectFromS3(bucket, s3ObjectPath)
+			awsVerifier.DeleteObjectFromS3(bucket, pathToDriftState)
+		})
+
+		It("returns the latest version without a drift marker when nothing has changed", func() {
+			runner := check.Runner{
+				LogWriter: GinkgoWriter,
+			}
+			resp, err := runner.Run(checkInput)
+			Expect(err).ToNot(HaveOccurred())
+
+			expectOutput := []models.Version{
+				driftCheckVersion,
+			}
+			Expect(resp).To(Equal(expectOutput))
+		})
+
+		It("returns a version with a drift marker when resources are changed outside of Terraform", func() {
+			awsVerifier.DeleteObjectFromS3(bucket, s3ObjectPath)
+
+			runner := check.Runner{
+				LogWriter: GinkgoWriter,
+			}
+			resp, err := runner.Run(checkInput)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(resp).To(HaveLen(1))
+			Expect(resp[0].EnvName).To(Equal(driftEnvName))
+			Expect(resp[0].Serial).To(Equal(driftCheckVersion.Serial))
+			Expect(resp[0].Lineage).To(Equal(driftCheckVersion.Lineage))
+			Expect(resp[0].Drift).To(Equal("true"))
+			Expect(resp[0].DriftChecksum).ToNot(BeEmpty())
+
+			By("returning the same version while the drift is unchanged")
+			secondResp, err := runner.Run(checkInput)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secondResp).To(Equal(resp))
+		})
+	})
 })
@@ -0,0 +1,35 @@
+package check_test
+
+import (
+	"io/ioutil"
+
+	"github.com/ljfranklin/terraform-resource/check"
+	"github.com/ljfranklin/terraform-resource/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Check with env_name_rules", func() {
+
+	It("returns an error if `env_name` breaks the rules", func() {
+		checkReq := models.InRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType: "s3",
+				},
+				EnvName: "Staging_1",
+				EnvNameRules: models.EnvNameRules{
+					Case:           models.LowerCase,
+					AllowedPattern: "^[a-z-]+$",
+				},
+			},
+		}
+
+		runner := check.Runner{
+			LogWriter: ioutil.Discard,
+		}
+		_, err := runner.Run(checkReq)
+		Expect(err).To(MatchError("Invalid env name 'staging_1': must match `env_name_rules.allowed_pattern` '^[a-z-]+$'"))
+	})
+})
@@ -0,0 +1,36 @@
+package check_test
+
+import (
+	"io/ioutil"
+
+	"github.com/ljfranklin/terraform-resource/check"
+	"github.com/ljfranklin/terraform-resource/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Check with a reap version", func() {
+
+	It("does not treat `reap` as an env to check", func() {
+		checkReq := models.InRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType: "s3",
+				},
+			},
+			Version: models.Version{
+				EnvName:      models.ReapAction,
+				LastModified: "2020-01-10T00:00:00Z",
+				Reap:         "true",
+			},
+		}
+
+		runner := check.Runner{
+			LogWriter: ioutil.Discard,
+		}
+		versions, err := runner.Run(checkReq)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(versions).To(BeEmpty())
+	})
+})
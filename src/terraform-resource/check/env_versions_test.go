@@ -0,0 +1,75 @@
+package check_test
+
+import (
+	"github.com/ljfranklin/terraform-resource/check"
+	"github.com/ljfranklin/terraform-resource/models"
+	"github.com/ljfranklin/terraform-resource/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EnvVersions", func() {
+
+	var latestVersions map[string]terraform.StateVersion
+
+	const snapshot = "pr-1:2:aaaaa,pr-5:7:bbbbb,pr-9:4:ccccc"
+
+	BeforeEach(func() {
+		latestVersions = map[string]terraform.StateVersion{
+			"pr-9": {Serial: 4, Lineage: "ccccc"},
+			"pr-1": {Serial: 2, Lineage: "aaaaa"},
+			"pr-5": {Serial: 7, Lineage: "bbbbb"},
+		}
+	})
+
+	It("reports every env sorted by name when no version is given", func() {
+		Expect(check.EnvVersions(latestVersions, models.Version{})).To(Equal([]models.Version{
+			{EnvName: "pr-1", Serial: "2", Lineage: "aaaaa", CheckedEnvs: snapshot},
+			{EnvName: "pr-5", Serial: "7", Lineage: "bbbbb", CheckedEnvs: snapshot},
+			{EnvName: "pr-9", Serial: "4", Lineage: "ccccc", CheckedEnvs: snapshot},
+		}))
+	})
+
+	It("reports only the current version if no env has changed", func() {
+		current := models.Version{EnvName: "pr-5", Serial: "7", Lineage: "bbbbb", CheckedEnvs: snapshot}
+
+		Expect(check.EnvVersions(latestVersions, current)).To(Equal([]models.Version{current}))
+	})
+
+	It("reports the current version first followed only by the changed envs", func() {
+		current := models.Version{EnvName: "pr-9", Serial: "4", Lineage: "ccccc", CheckedEnvs: "pr-1:1:aaaaa,pr-5:7:bbbbb,pr-9:4:ccccc"}
+
+		Expect(check.EnvVersions(latestVersions, current)).To(Equal([]models.Version{
+			current,
+			{EnvName: "pr-1", Serial: "2", Lineage: "aaaaa", CheckedEnvs: snapshot},
+		}))
+	})
+
+	It("reports the new version of the current env after the current version", func() {
+		current := models.Version{EnvName: "pr-1", Serial: "1", Lineage: "aaaaa", CheckedEnvs: "pr-1:1:aaaaa,pr-5:7:bbbbb,pr-9:4:ccccc"}
+
+		Expect(check.EnvVersions(latestVersions, current)).To(Equal([]models.Version{
+			current,
+			{EnvName: "pr-1", Serial: "2", Lineage: "aaaaa", CheckedEnvs: snapshot},
+		}))
+	})
+
+	It("reports new envs and skips the current version of a deleted env", func() {
+		current := models.Version{EnvName: "pr-3", Serial: "5", Lineage: "ddddd", CheckedEnvs: "pr-1:2:aaaaa,pr-3:5:ddddd,pr-5:7:bbbbb"}
+
+		Expect(check.EnvVersions(latestVersions, current)).To(Equal([]models.Version{
+			{EnvName: "pr-9", Serial: "4", Lineage: "ccccc", CheckedEnvs: snapshot},
+		}))
+	})
+
+	It("reports every other env once if the current version has no `checked_envs`", func() {
+		current := models.Version{EnvName: "pr-5", Serial: "7", Lineage: "bbbbb"}
+
+		Expect(check.EnvVersions(latestVersions, current)).To(Equal([]models.Version{
+			current,
+			{EnvName: "pr-1", Serial: "2", Lineage: "aaaaa", CheckedEnvs: snapshot},
+			{EnvName: "pr-9", Serial: "4", Lineage: "ccccc", CheckedEnvs: snapshot},
+		}))
+	})
+})
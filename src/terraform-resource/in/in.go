@@ -1,8 +1,6 @@
 package in
 
 import (
-	"compress/gzip"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -10,13 +8,15 @@ import (
 	"os"
 	"path"
 	"strconv"
-	"strings"
 
 	"github.com/ljfranklin/terraform-resource/encoder"
+	"github.com/ljfranklin/terraform-resource/jsonplan"
 	"github.com/ljfranklin/terraform-resource/logger"
 	"github.com/ljfranklin/terraform-resource/models"
+	"github.com/ljfranklin/terraform-resource/outputs"
 	"github.com/ljfranklin/terraform-resource/storage"
 	"github.com/ljfranklin/terraform-resource/terraform"
+	"github.com/ljfranklin/terraform-resource/workspaces"
 )
 
 type Runner struct {
@@ -26,6 +26,9 @@ type Runner struct {
 
 type EnvNotFoundError error
 
+// listEnvsWorkers bounds how many states are fetched at once with `list_envs`
+const listEnvsWorkers = 4
+
 var ErrOutputModule error = errors.New("the `output_module` feature was removed in Terraform 0.12.0, you must now explicitly declare all outputs in the root module")
 
 func (r Runner) Run(req models.InRequest) (models.InResponse, error) {
@@ -33,13 +36,22 @@ func (r Runner) Run(req models.InRequest) (models.InResponse, error) {
 		return models.InResponse{}, fmt.Errorf("Invalid Version request: %s", err)
 	}
 
+	if !req.Version.IsReap() {
+		// `put` applies `env_name_rules`, so the env must be looked up by the normalized name
+		envName, err := req.Source.EnvNameRules.Normalize(req.Version.EnvName)
+		if err != nil {
+			return models.InResponse{}, err
+		}
+		req.Version.EnvName = envName
+	}
+
 	envName := req.Version.EnvName
 	nameFilepath := path.Join(r.OutputDir, "name")
 	if err := ioutil.WriteFile(nameFilepath, []byte(envName), 0644); err != nil {
 		return models.InResponse{}, fmt.Errorf("Failed to create name file at path '%s': %s", nameFilepath, err)
 	}
 
-	if req.Params.Action == models.DestroyAction {
+	if req.Params.Action == models.DestroyAction || req.Params.Action == models.ReapAction || req.Version.IsReap() {
 		resp := models.InResponse{
 			Version: req.Version,
 		}
@@ -50,6 +62,33 @@ func (r Runner) Run(req models.InRequest) (models.InResponse, error) {
 		return models.InResponse{}, err
 	}
 
+	if req.Params.Terraform.WorkspacePrefix != "" {
+		return models.InResponse{}, errors.New("`workspace_prefix` can only be set in `source`, as `check` and `get` must use the same prefix as `put`")
+	}
+
+	if err := outputs.ValidateFormats(req.Params.OutputFormats); err != nil {
+		return models.InResponse{}, err
+	}
+
+	switch req.Params.SensitiveOutputs {
+	case "", models.IncludeSensitiveOutputs, models.SeparateSensitiveOutputs, models.OmitSensitiveOutputs:
+	default:
+		return models.InResponse{}, fmt.Errorf(
+			"Unknown value for `sensitive_outputs`: '%s', Supported values: '%s', '%s', '%s'",
+			req.Params.SensitiveOutputs,
+			models.IncludeSensitiveOutputs,
+			models.SeparateSensitiveOutputs,
+			models.OmitSensitiveOutputs,
+		)
+	}
+
+	if req.Params.ListEnvs {
+		if req.Source.BackendType == "" {
+			return models.InResponse{}, errors.New("`list_envs` is only supported with `backend_type`")
+		}
+		return r.listEnvs(req)
+	}
+
 	tmpDir, err := ioutil.TempDir(os.TempDir(), "terraform-resource-in")
 	if err != nil {
 		return models.InResponse{}, fmt.Errorf("Failed to create tmp dir at '%s'", os.TempDir())
@@ -111,8 +150,15 @@ func (r Runner) inWithBackend(req models.InRequest, tmpDir string) (models.InRes
 	}
 
 	if req.Version.IsPlan() {
-		if req.Params.OutputJSONPlanfile {
-			if err := r.writeJSONPlanToFile(targetEnvName+"-plan", client); err != nil {
+		// allows `put` to verify the reviewed plan via `plan_checksum_file`
+		if req.Version.PlanChecksum != "" {
+			if err := r.writePlanChecksumToFile(req.Version.PlanChecksum); err != nil {
+				return models.InResponse{}, err
+			}
+		}
+
+		if req.Params.OutputJSONPlanfile || req.Params.OutputPlanText || req.Params.OutputPlanSummary {
+			if err := r.writePlanFiles(terraform.PlanWorkspaceName(terraformModel.WorkspaceName(targetEnvName), req.Version.PlanName), req.Version.PlanChecksum, req.Params, terraformModel, client); err != nil {
 				return models.InResponse{}, err
 			}
 		}
@@ -137,12 +183,52 @@ func (r Runner) inWithBackend(req models.InRequest, tmpDir string) (models.InRes
 	return r.writeBackendOutputs(req, targetEnvName, client)
 }
 
+// listEnvs writes an inventory of all envs in the backend to `envs.json`
+// instead of fetching the outputs of the requested version
+func (r Runner) listEnvs(req models.InRequest) (models.InResponse, error) {
+	terraformModel := req.Source.Terraform.Merge(req.Params.Terraform)
+	if err := terraformModel.Validate(); err != nil {
+		return models.InResponse{}, fmt.Errorf("Failed to validate terraform Model: %s", err)
+	}
+	terraformModel.Source = "."
+
+	client := terraform.NewClient(
+		terraformModel,
+		r.LogWriter,
+	)
+
+	envs, err := workspaces.NewWithPrefix(client, req.Source.WorkspacePrefix).List(listEnvsWorkers)
+	if err != nil {
+		return models.InResponse{}, fmt.Errorf("Failed to list envs: %s", err)
+	}
+
+	envsFilepath := path.Join(r.OutputDir, "envs.json")
+	envsFile, err := os.Create(envsFilepath)
+	if err != nil {
+		return models.InResponse{}, fmt.Errorf("Failed to create envs file at path '%s': %s", envsFilepath, err)
+	}
+	defer envsFile.Close()
+
+	if err = encoder.NewJSONEncoder(envsFile).Encode(envs); err != nil {
+		return models.InResponse{}, fmt.Errorf("Failed to write envs file: %s", err)
+	}
+
+	resp := models.InResponse{
+		Version: req.Version,
+		Metadata: []models.MetadataField{
+			{Name: "env_count", Value: strconv.Itoa(len(envs))},
+		},
+	}
+	return resp, nil
+}
+
 func (r Runner) writeBackendOutputs(req models.InRequest, targetEnvName string, client terraform.Client) (models.InResponse, error) {
-	if err := r.ensureEnvExistsInBackend(targetEnvName, client); err != nil {
+	workspace := req.Source.Terraform.WorkspaceName(targetEnvName)
+	if err := r.ensureEnvExistsInBackend(workspace, client); err != nil {
 		return models.InResponse{}, err
 	}
 
-	tfOutput, err := client.Output(targetEnvName)
+	tfOutput, err := client.Output(workspace)
 	if err != nil {
 		return models.InResponse{}, fmt.Errorf("Failed to parse terraform output.\nError: %s", err)
 	}
@@ -150,21 +236,21 @@ func (r Runner) writeBackendOutputs(req models.InRequest, targetEnvName string,
 		Output: tfOutput,
 	}
 
-	if err = r.writeRawOutputToFile(result); err != nil {
+	if err = r.writeOutputFiles(result, req.Params); err != nil {
 		return models.InResponse{}, err
 	}
 
 	if req.Params.OutputStatefile {
-		if err = r.writeBackendStateToFile(targetEnvName, client); err != nil {
+		if err = r.writeBackendStateToFile(workspace, client); err != nil {
 			return models.InResponse{}, err
 		}
 	}
-	stateVersion, err := client.CurrentStateVersion(targetEnvName)
+	stateVersion, err := client.CurrentStateVersion(workspace)
 	if err != nil {
 		return models.InResponse{}, err
 	}
 
-	metadata, err := r.sanitizedOutput(result, client)
+	metadata, err := r.sanitizedOutput(result, req.Source.Terraform.Merge(req.Params.Terraform), client)
 	if err != nil {
 		return models.InResponse{}, err
 	}
@@ -174,6 +260,9 @@ func (r Runner) writeBackendOutputs(req models.InRequest, targetEnvName string,
 			EnvName: targetEnvName,
 			Serial:  strconv.Itoa(stateVersion.Serial),
 			Lineage: stateVersion.Lineage,
+			// drift is only detected by `check`, so preserve it on the fetched version
+			Drift:         req.Version.Drift,
+			DriftChecksum: req.Version.DriftChecksum,
 		},
 		Metadata: metadata,
 	}
@@ -208,20 +297,79 @@ func (r Runner) writeNameToFile(envName string) error {
 	return ioutil.WriteFile(nameFilepath, []byte(envName), 0644)
 }
 
-func (r Runner) writeRawOutputToFile(result terraform.Result) error {
-	outputFilepath := path.Join(r.OutputDir, "metadata")
-	outputFile, err := os.Create(outputFilepath)
+func (r Runner) writePlanChecksumToFile(checksum string) error {
+	checksumFilepath := path.Join(r.OutputDir, "plan_checksum")
+	return ioutil.WriteFile(checksumFilepath, []byte(checksum), 0644)
+}
+
+func (r Runner) writeOutputFiles(result terraform.Result, params models.InParams) error {
+	rawOutputs := result.RawOutput()
+	sensitiveOutputs := map[string]interface{}{}
+	if params.SensitiveOutputs == models.SeparateSensitiveOutputs || params.SensitiveOutputs == models.OmitSensitiveOutputs {
+		rawOutputs, sensitiveOutputs = result.SplitRawOutput()
+	}
+
+	if err := r.writeRawOutputToFile(rawOutputs, "metadata", 0644); err != nil {
+		return err
+	}
+
+	if params.SensitiveOutputs == models.SeparateSensitiveOutputs {
+		if err := r.writeRawOutputToFile(sensitiveOutputs, "sensitive_metadata", 0600); err != nil {
+			return err
+		}
+	}
+
+	for _, format := range params.OutputFormats {
+		if err := r.writeFormattedOutputToFile(rawOutputs, format); err != nil {
+			return err
+		}
+	}
+
+	if params.OutputFiles {
+		if err := outputs.WriteFiles(path.Join(r.OutputDir, "outputs"), rawOutputs, 0644); err != nil {
+			return fmt.Errorf("Failed to write output files: %s", err)
+		}
+
+		if params.SensitiveOutputs == models.SeparateSensitiveOutputs {
+			if err := outputs.WriteFiles(path.Join(r.OutputDir, "sensitive_outputs"), sensitiveOutputs, 0600); err != nil {
+				return fmt.Errorf("Failed to write sensitive output files: %s", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r Runner) writeRawOutputToFile(rawOutputs map[string]interface{}, fileName string, perm os.FileMode) error {
+	outputFilepath := path.Join(r.OutputDir, fileName)
+	outputFile, err := os.OpenFile(outputFilepath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
 	if err != nil {
 		return fmt.Errorf("Failed to create output file at path '%s': %s", outputFilepath, err)
 	}
+	defer outputFile.Close()
 
-	if err = encoder.NewJSONEncoder(outputFile).Encode(result.RawOutput()); err != nil {
+	if err = encoder.NewJSONEncoder(outputFile).Encode(rawOutputs); err != nil {
 		return fmt.Errorf("Failed to write output file: %s", err)
 	}
 
 	return nil
 }
 
+func (r Runner) writeFormattedOutputToFile(rawOutputs map[string]interface{}, format string) error {
+	outputFilepath := path.Join(r.OutputDir, fmt.Sprintf("metadata.%s", format))
+	outputFile, err := os.Create(outputFilepath)
+	if err != nil {
+		return fmt.Errorf("Failed to create output file at path '%s': %s", outputFilepath, err)
+	}
+	defer outputFile.Close()
+
+	if err = outputs.Write(outputFile, format, rawOutputs); err != nil {
+		return fmt.Errorf("Failed to write output file '%s': %s", outputFilepath, err)
+	}
+
+	return nil
+}
+
 func (r Runner) writeBackendStateToFile(envName string, client terraform.Client) error {
 	stateFilePath := path.Join(r.OutputDir, "terraform.tfstate")
 	stateContents, err := client.StatePull(envName)
@@ -231,46 +379,43 @@ func (r Runner) writeBackendStateToFile(envName string, client terraform.Client)
 	return ioutil.WriteFile(stateFilePath, stateContents, 0777)
 }
 
-func (r Runner) writeJSONPlanToFile(envName string, client terraform.Client) error {
-	tfOutput, err := client.Output(envName)
+func (r Runner) writePlanFiles(planEnvName string, checksum string, params models.InParams, model models.Terraform, client terraform.Client) error {
+	plan, err := terraform.ReadStoredPlan(client, model, planEnvName, checksum)
 	if err != nil {
 		return err
 	}
 
-	planFilePath := path.Join(r.OutputDir, "plan.json")
-
-	var encodedPlan string
-	if val, ok := tfOutput[models.PlanContentJSON]; ok {
-		encodedPlan = val["value"].(string)
-	} else {
-		return fmt.Errorf("state has no output for key %s", models.PlanContentJSON)
+	if params.OutputJSONPlanfile {
+		if err = ioutil.WriteFile(path.Join(r.OutputDir, "plan.json"), plan.JSONPlan, 0600); err != nil {
+			return err
+		}
 	}
 
-	// Base64 decode then gunzip the JSON plan
-	rawPlanReader := strings.NewReader(encodedPlan)
-	decodedReader := base64.NewDecoder(base64.StdEncoding, rawPlanReader)
-	zr, err := gzip.NewReader(decodedReader)
-	if err != nil {
-		return err
-	}
-	outputFile, err := os.OpenFile(planFilePath, os.O_RDWR|os.O_CREATE, 0600)
-	if err != nil {
-		return err
+	if params.OutputPlanText {
+		if plan.TextPlan == nil {
+			return errors.New("Plans created by older versions of this resource must be re-run with `plan_only` to output `plan.txt`")
+		}
+		if err = ioutil.WriteFile(path.Join(r.OutputDir, "plan.txt"), plan.TextPlan, 0600); err != nil {
+			return err
+		}
 	}
 
-	if _, err := io.Copy(outputFile, zr); err != nil {
-		return err
+	if params.OutputPlanSummary {
+		if err = r.writePlanSummaryToFile(plan.JSONPlan); err != nil {
+			return err
+		}
 	}
 
-	if err := zr.Close(); err != nil {
-		return err
-	}
+	return nil
+}
 
-	if err := outputFile.Close(); err != nil {
-		return err
+func (r Runner) writePlanSummaryToFile(jsonPlanContents []byte) error {
+	plan, err := jsonplan.Parse(jsonPlanContents)
+	if err != nil {
+		return fmt.Errorf("Failed to parse JSON plan: %s", err)
 	}
 
-	return nil
+	return ioutil.WriteFile(path.Join(r.OutputDir, "plan_summary.md"), []byte(plan.Markdown()), 0600)
 }
 
 func (r Runner) writeLegacyStateToFile(localStatefilePath string) error {
@@ -282,14 +427,8 @@ func (r Runner) writeLegacyStateToFile(localStatefilePath string) error {
 	return ioutil.WriteFile(stateFilePath, stateContents, 0777)
 }
 
-func (r Runner) sanitizedOutput(result terraform.Result, client terraform.Client) ([]models.MetadataField, error) {
-	metadata := []models.MetadataField{}
-	for key, value := range result.SanitizedOutput() {
-		metadata = append(metadata, models.MetadataField{
-			Name:  key,
-			Value: value,
-		})
-	}
+func (r Runner) sanitizedOutput(result terraform.Result, model models.Terraform, client terraform.Client) ([]models.MetadataField, error) {
+	metadata := result.Metadata(model)
 
 	tfVersion, err := client.Version()
 	if err != nil {
@@ -355,7 +494,7 @@ func (r Runner) inWithLegacyStorage(req models.InRequest, tmpDir string) (models
 		Output: tfOutput,
 	}
 
-	if err = r.writeRawOutputToFile(result); err != nil {
+	if err = r.writeOutputFiles(result, req.Params); err != nil {
 		return models.InResponse{}, err
 	}
 
@@ -365,7 +504,7 @@ func (r Runner) inWithLegacyStorage(req models.InRequest, tmpDir string) (models
 		}
 	}
 
-	metadata, err := r.sanitizedOutput(result, client)
+	metadata, err := r.sanitizedOutput(result, req.Source.Terraform.Merge(req.Params.Terraform), client)
 	if err != nil {
 		return models.InResponse{}, err
 	}
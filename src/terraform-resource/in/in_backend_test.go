@@ -200,6 +200,142 @@ var _ = Describe("In with Backend", func() {
 			Expect(string(stateContents)).To(ContainSubstring("previous"))
 		})
 
+		It("outputs the metadata in each of the `output_formats`", func() {
+			inReq.Params.OutputFormats = []string{"yml", "env", "tfvars.json"}
+			inReq.Version = models.Version{
+				EnvName: prevEnvName,
+				Serial:  "0",
+			}
+
+			runner := in.Runner{
+				OutputDir: tmpDir,
+			}
+			_, err := runner.Run(inReq)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(path.Join(tmpDir, "metadata")).To(BeAnExistingFile())
+
+			yamlContents, err := ioutil.ReadFile(path.Join(tmpDir, "metadata.yml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(yamlContents)).To(ContainSubstring("env_name: previous\n"))
+
+			envContents, err := ioutil.ReadFile(path.Join(tmpDir, "metadata.env"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(envContents)).To(ContainSubstring("env_name='previous'\n"))
+			Expect(string(envContents)).To(ContainSubstring("map_key_1='value-1'\n"))
+			Expect(string(envContents)).To(ContainSubstring("list_0='item-1'\n"))
+
+			tfvarsContents, err := ioutil.ReadFile(path.Join(tmpDir, "metadata.tfvars.json"))
+			Expect(err).ToNot(HaveOccurred())
+			tfvars := map[string]interface{}{}
+			Expect(json.Unmarshal(tfvarsContents, &tfvars)).To(Succeed())
+			Expect(tfvars["env_name"]).To(Equal("previous"))
+		})
+
+		It("writes each output to its own file if `output_files` is given", func() {
+			inReq.Params.OutputFiles = true
+			inReq.Version = models.Version{
+				EnvName: prevEnvName,
+				Serial:  "0",
+			}
+
+			runner := in.Runner{
+				OutputDir: tmpDir,
+			}
+			_, err := runner.Run(inReq)
+			Expect(err).ToNot(HaveOccurred())
+
+			envNameContents, err := ioutil.ReadFile(path.Join(tmpDir, "outputs", "env_name"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(envNameContents)).To(Equal("previous"))
+
+			mapContents, err := ioutil.ReadFile(path.Join(tmpDir, "outputs", "map"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mapContents).To(MatchJSON(`{"key-1": "value-1", "key-2": "value-2"}`))
+		})
+
+		It("writes sensitive outputs to `sensitive_metadata` if `sensitive_outputs: separate` is given", func() {
+			inReq.Params.SensitiveOutputs = "separate"
+			inReq.Params.OutputFiles = true
+			inReq.Version = models.Version{
+				EnvName: prevEnvName,
+				Serial:  "0",
+			}
+
+			runner := in.Runner{
+				OutputDir: tmpDir,
+			}
+			_, err := runner.Run(inReq)
+			Expect(err).ToNot(HaveOccurred())
+
+			metadataContents, err := ioutil.ReadFile(path.Join(tmpDir, "metadata"))
+			Expect(err).ToNot(HaveOccurred())
+			metadata := map[string]interface{}{}
+			Expect(json.Unmarshal(metadataContents, &metadata)).To(Succeed())
+			Expect(metadata["env_name"]).To(Equal("previous"))
+			Expect(metadata).ToNot(HaveKey("secret"))
+			Expect(path.Join(tmpDir, "outputs", "secret")).ToNot(BeAnExistingFile())
+
+			sensitivePath := path.Join(tmpDir, "sensitive_metadata")
+			sensitiveInfo, err := os.Stat(sensitivePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sensitiveInfo.Mode().Perm()).To(Equal(os.FileMode(0600)))
+			sensitiveContents, err := ioutil.ReadFile(sensitivePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sensitiveContents).To(MatchJSON(`{"secret": "super-secret"}`))
+
+			secretContents, err := ioutil.ReadFile(path.Join(tmpDir, "sensitive_outputs", "secret"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(secretContents)).To(Equal("super-secret"))
+		})
+
+		It("leaves out sensitive outputs if `sensitive_outputs: omit` is given", func() {
+			inReq.Params.SensitiveOutputs = "omit"
+			inReq.Version = models.Version{
+				EnvName: prevEnvName,
+				Serial:  "0",
+			}
+
+			runner := in.Runner{
+				OutputDir: tmpDir,
+			}
+			_, err := runner.Run(inReq)
+			Expect(err).ToNot(HaveOccurred())
+
+			metadataContents, err := ioutil.ReadFile(path.Join(tmpDir, "metadata"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(metadataContents)).ToNot(ContainSubstring("super-secret"))
+			Expect(path.Join(tmpDir, "sensitive_metadata")).ToNot(BeAnExistingFile())
+		})
+
+		It("returns an error for unknown `output_formats`", func() {
+			inReq.Params.OutputFormats = []string{"toml"}
+			inReq.Version = models.Version{
+				EnvName: prevEnvName,
+				Serial:  "0",
+			}
+
+			runner := in.Runner{
+				OutputDir: tmpDir,
+			}
+			_, err := runner.Run(inReq)
+			Expect(err).To(MatchError(ContainSubstring("Unknown value for `output_formats`: 'toml'")))
+		})
+
+		It("returns an error if `workspace_prefix` is given in params", func() {
+			inReq.Params.Terraform.WorkspacePrefix = "team-a-"
+			inReq.Version = models.Version{
+				EnvName: prevEnvName,
+				Serial:  "0",
+			}
+
+			runner := in.Runner{
+				OutputDir: tmpDir,
+			}
+			_, err := runner.Run(inReq)
+			Expect(err).To(MatchError(ContainSubstring("`workspace_prefix` can only be set in `source`")))
+		})
+
 		It("returns an error when OutputModule is used", func() {
 			inReq.Params.OutputModule = "module_1"
 			inReq.Version = models.Version{
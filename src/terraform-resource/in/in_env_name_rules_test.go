@@ -0,0 +1,77 @@
+package in_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/ljfranklin/terraform-resource/in"
+	"github.com/ljfranklin/terraform-resource/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("In with env_name_rules", func() {
+
+	var (
+		tmpDir string
+		inReq  models.InRequest
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir(os.TempDir(), "terraform-resource-in-test")
+		Expect(err).ToNot(HaveOccurred())
+
+		inReq = models.InRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType: "s3",
+				},
+				EnvNameRules: models.EnvNameRules{
+					Case:           models.LowerCase,
+					AllowedPattern: "^[a-z-]+$",
+				},
+			},
+			Params: models.InParams{
+				Action: models.DestroyAction,
+			},
+		}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	It("looks up the env by its normalized name", func() {
+		inReq.Version = models.Version{
+			EnvName: "Staging",
+			Serial:  "1",
+		}
+
+		runner := in.Runner{
+			OutputDir: tmpDir,
+		}
+		resp, err := runner.Run(inReq)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(resp.Version.EnvName).To(Equal("staging"))
+		name, err := ioutil.ReadFile(path.Join(tmpDir, "name"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(name)).To(Equal("staging"))
+	})
+
+	It("returns an error if the env name breaks the rules", func() {
+		inReq.Version = models.Version{
+			EnvName: "staging_1",
+			Serial:  "1",
+		}
+
+		runner := in.Runner{
+			OutputDir: tmpDir,
+		}
+		_, err := runner.Run(inReq)
+		Expect(err).To(MatchError(ContainSubstring("must match `env_name_rules.allowed_pattern`")))
+	})
+})
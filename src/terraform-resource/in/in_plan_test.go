@@ -269,6 +269,10 @@ var _ = Describe("JSON Plan", func() {
 		expectedNamePath := path.Join(inDir, "name")
 		Expect(expectedNamePath).To(BeAnExistingFile())
 
+		checksumContents, err := ioutil.ReadFile(path.Join(inDir, "plan_checksum"))
+		Expect(err).To(BeNil())
+		Expect(string(checksumContents)).To(Equal(planOutput.Version.PlanChecksum))
+
 		expectedPlanPath := path.Join(inDir, "plan.json")
 		Expect(expectedPlanPath).To(BeAnExistingFile())
 
@@ -277,6 +281,25 @@ var _ = Describe("JSON Plan", func() {
 		Expect(string(stateContents)).To(ContainSubstring("variables"))
 		Expect(string(stateContents)).To(ContainSubstring("output_changes"))
 		Expect(string(stateContents)).To(ContainSubstring("resource_changes"))
+
+		By("outputs the rendered plan and summary if `output_plan_text` and `output_plan_summary` are given")
+
+		inReq.Params = models.InParams{
+			OutputPlanText:    true,
+			OutputPlanSummary: true,
+		}
+		_, err = runner.Run(inReq)
+		Expect(err).ToNot(HaveOccurred())
+
+		textContents, err := ioutil.ReadFile(path.Join(inDir, "plan.txt"))
+		Expect(err).To(BeNil())
+		Expect(string(textContents)).To(ContainSubstring("aws_s3_bucket_object.s3_object will be created"))
+		Expect(string(textContents)).To(ContainSubstring("Plan: 1 to add, 0 to change, 0 to destroy."))
+
+		summaryContents, err := ioutil.ReadFile(path.Join(inDir, "plan_summary.md"))
+		Expect(err).To(BeNil())
+		Expect(string(summaryContents)).To(ContainSubstring("**Plan:** 1 to add, 0 to change, 0 to destroy, 0 to replace."))
+		Expect(string(summaryContents)).To(ContainSubstring("| `+` create | `aws_s3_bucket_object.s3_object` | `aws_s3_bucket_object` |"))
 	})
 
 	It("HACK: outputs metadata file if statefile exists", func() {
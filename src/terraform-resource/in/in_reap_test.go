@@ -0,0 +1,52 @@
+package in_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/ljfranklin/terraform-resource/in"
+	"github.com/ljfranklin/terraform-resource/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("In with a reap version", func() {
+
+	var tmpDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir(os.TempDir(), "terraform-resource-in-test")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	It("returns the version without fetching any state, even without `action: reap` in `get_params`", func() {
+		inReq := models.InRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType: "s3",
+				},
+			},
+			Version: models.Version{
+				EnvName:      models.ReapAction,
+				LastModified: "2020-01-10T00:00:00Z",
+				Reap:         "true",
+			},
+		}
+
+		runner := in.Runner{
+			OutputDir: tmpDir,
+		}
+		resp, err := runner.Run(inReq)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(resp.Version).To(Equal(inReq.Version))
+		Expect(path.Join(tmpDir, "metadata")).ToNot(BeAnExistingFile())
+	})
+})
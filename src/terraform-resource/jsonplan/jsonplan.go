@@ -0,0 +1,188 @@
+package jsonplan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+// Plan is the subset of the `terraform show -json` plan format
+// that the resource inspects.
+type Plan struct {
+	ResourceChanges []ResourceChange `json:"resource_changes"`
+	ResourceDrift   []ResourceChange `json:"resource_drift"`
+}
+
+type ResourceChange struct {
+	Address string `json:"address"`
+	Mode    string `json:"mode"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Change  Change `json:"change"`
+}
+
+type Change struct {
+	Actions      []string    `json:"actions"`
+	Before       interface{} `json:"before"`
+	After        interface{} `json:"after"`
+	AfterUnknown interface{} `json:"after_unknown"`
+}
+
+const (
+	ManagedMode = "managed"
+
+	NoOpAction   = "no-op"
+	CreateAction = "create"
+	ReadAction   = "read"
+	UpdateAction = "update"
+	DeleteAction = "delete"
+)
+
+func Read(planPath string) (Plan, error) {
+	rawPlan, err := ioutil.ReadFile(planPath)
+	if err != nil {
+		return Plan{}, fmt.Errorf("Failed to read JSON planfile at '%s': %s", planPath, err)
+	}
+
+	plan, err := Parse(rawPlan)
+	if err != nil {
+		return Plan{}, fmt.Errorf("Failed to unmarshal JSON planfile at '%s': %s", planPath, err)
+	}
+
+	return plan, nil
+}
+
+func Parse(rawPlan []byte) (Plan, error) {
+	plan := Plan{}
+	if err := json.Unmarshal(rawPlan, &plan); err != nil {
+		return Plan{}, err
+	}
+
+	return plan, nil
+}
+
+func (r ResourceChange) IsManaged() bool {
+	return r.Mode == ManagedMode
+}
+
+func (r ResourceChange) IsCreate() bool {
+	return r.Change.hasActions(CreateAction)
+}
+
+func (r ResourceChange) IsUpdate() bool {
+	return r.Change.hasActions(UpdateAction)
+}
+
+func (r ResourceChange) IsDelete() bool {
+	return r.Change.hasActions(DeleteAction)
+}
+
+// IsReplace is true for both create-before-destroy and destroy-before-create
+func (r ResourceChange) IsReplace() bool {
+	return r.Change.hasActions(DeleteAction, CreateAction) || r.Change.hasActions(CreateAction, DeleteAction)
+}
+
+func (c Change) hasActions(actions ...string) bool {
+	if len(c.Actions) != len(actions) {
+		return false
+	}
+	for i := range actions {
+		if c.Actions[i] != actions[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// DriftedAddresses returns the sorted addresses of all resources which
+// were changed outside of Terraform.
+func (p Plan) DriftedAddresses() []string {
+	addresses := []string{}
+	for _, drift := range p.ResourceDrift {
+		addresses = append(addresses, drift.Address)
+	}
+	sort.Strings(addresses)
+
+	return addresses
+}
+
+// Summary mirrors the "Plan: X to add, Y to change, Z to destroy" line printed
+// by Terraform, so replaced resources are counted as both added and destroyed.
+type Summary struct {
+	Add     int
+	Change  int
+	Destroy int
+	Replace int
+	// sorted addresses prefixed with Terraform's change symbol, e.g. `-/+ aws_instance.web`
+	Addresses []string
+}
+
+func (s Summary) HasChanges() bool {
+	return len(s.Addresses) > 0
+}
+
+func (p Plan) Summary() Summary {
+	summary := Summary{
+		Addresses: []string{},
+	}
+	for _, change := range p.managedChanges() {
+		switch change.Action() {
+		case ReplaceAction:
+			summary.Add++
+			summary.Destroy++
+			summary.Replace++
+		case CreateAction:
+			summary.Add++
+		case UpdateAction:
+			summary.Change++
+		case DeleteAction:
+			summary.Destroy++
+		default:
+			continue
+		}
+		summary.Addresses = append(summary.Addresses, fmt.Sprintf("%s %s", actionSymbols[change.Action()], change.Address))
+	}
+
+	return summary
+}
+
+// ReplaceAction is not a Terraform action but a combination of create and delete
+const ReplaceAction = "replace"
+
+var actionSymbols = map[string]string{
+	CreateAction:  "+",
+	UpdateAction:  "~",
+	DeleteAction:  "-",
+	ReplaceAction: "-/+",
+}
+
+// Action returns a single action describing the change, or NoOpAction
+// for reads and unchanged resources
+func (r ResourceChange) Action() string {
+	switch {
+	case r.IsReplace():
+		return ReplaceAction
+	case r.IsCreate():
+		return CreateAction
+	case r.IsUpdate():
+		return UpdateAction
+	case r.IsDelete():
+		return DeleteAction
+	}
+	return NoOpAction
+}
+
+// managedChanges returns the changes to managed resources sorted by address
+func (p Plan) managedChanges() []ResourceChange {
+	changes := []ResourceChange{}
+	for _, change := range p.ResourceChanges {
+		if change.IsManaged() {
+			changes = append(changes, change)
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Address < changes[j].Address
+	})
+	return changes
+}
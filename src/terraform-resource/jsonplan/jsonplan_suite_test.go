@@ -0,0 +1,13 @@
+package jsonplan_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestJSONPlan(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "JSONPlan Suite")
+}
@@ -0,0 +1,112 @@
+package jsonplan_test
+
+import (
+	"strings"
+
+	"github.com/ljfranklin/terraform-resource/jsonplan"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSONPlan", func() {
+
+	Describe("#Summary", func() {
+		change := func(address string, mode string, actions ...string) jsonplan.ResourceChange {
+			return jsonplan.ResourceChange{
+				Address: address,
+				Mode:    mode,
+				Change: jsonplan.Change{
+					Actions: actions,
+				},
+			}
+		}
+
+		It("counts replacements as both added and destroyed", func() {
+			plan := jsonplan.Plan{
+				ResourceChanges: []jsonplan.ResourceChange{
+					change("aws_instance.web", "managed", "create"),
+					change("aws_s3_bucket.logs", "managed", "delete"),
+					change("aws_db_instance.main", "managed", "delete", "create"),
+					change("aws_instance.cbd", "managed", "create", "delete"),
+					change("aws_iam_role.app", "managed", "update"),
+					change("random_id.unchanged", "managed", "no-op"),
+					change("data.aws_ami.ubuntu", "data", "read"),
+				},
+			}
+
+			Expect(plan.Summary()).To(Equal(jsonplan.Summary{
+				Add:     3,
+				Change:  1,
+				Destroy: 3,
+				Replace: 2,
+				Addresses: []string{
+					"-/+ aws_db_instance.main",
+					"~ aws_iam_role.app",
+					"-/+ aws_instance.cbd",
+					"+ aws_instance.web",
+					"- aws_s3_bucket.logs",
+				},
+			}))
+			Expect(plan.Summary().HasChanges()).To(BeTrue())
+		})
+
+		It("reports no changes for an empty plan", func() {
+			plan := jsonplan.Plan{
+				ResourceChanges: []jsonplan.ResourceChange{
+					change("random_id.unchanged", "managed", "no-op"),
+				},
+			}
+
+			Expect(plan.Summary().HasChanges()).To(BeFalse())
+			Expect(plan.Summary().Addresses).To(BeEmpty())
+		})
+	})
+
+	Describe("ResourceChange#Action", func() {
+		It("classifies each change by a single action", func() {
+			action := func(actions ...string) string {
+				return jsonplan.ResourceChange{Change: jsonplan.Change{Actions: actions}}.Action()
+			}
+
+			Expect(action("create")).To(Equal(jsonplan.CreateAction))
+			Expect(action("update")).To(Equal(jsonplan.UpdateAction))
+			Expect(action("delete")).To(Equal(jsonplan.DeleteAction))
+			Expect(action("delete", "create")).To(Equal(jsonplan.ReplaceAction))
+			Expect(action("create", "delete")).To(Equal(jsonplan.ReplaceAction))
+			Expect(action("read")).To(Equal(jsonplan.NoOpAction))
+			Expect(action("no-op")).To(Equal(jsonplan.NoOpAction))
+		})
+	})
+
+	Describe("#Markdown", func() {
+		It("renders changes grouped by action with the most destructive first", func() {
+			plan := jsonplan.Plan{
+				ResourceChanges: []jsonplan.ResourceChange{
+					{Address: "aws_instance.web", Mode: "managed", Type: "aws_instance", Change: jsonplan.Change{Actions: []string{"create"}}},
+					{Address: "aws_iam_role.app", Mode: "managed", Type: "aws_iam_role", Change: jsonplan.Change{Actions: []string{"update"}}},
+					{Address: `aws_s3_bucket.logs["a|b"]`, Mode: "managed", Type: "aws_s3_bucket", Change: jsonplan.Change{Actions: []string{"delete"}}},
+					{Address: "aws_db_instance.main", Mode: "managed", Type: "aws_db_instance", Change: jsonplan.Change{Actions: []string{"delete", "create"}}},
+					{Address: "random_id.unchanged", Mode: "managed", Type: "random_id", Change: jsonplan.Change{Actions: []string{"no-op"}}},
+				},
+			}
+
+			Expect(plan.Markdown()).To(Equal(strings.Join([]string{
+				"### Terraform Plan",
+				"",
+				"**Plan:** 2 to add, 1 to change, 2 to destroy, 1 to replace.",
+				"",
+				"| Action | Resource | Type |",
+				"| --- | --- | --- |",
+				"| `-/+` replace | `aws_db_instance.main` | `aws_db_instance` |",
+				"| `-` delete | `aws_s3_bucket.logs[\"a\\|b\"]` | `aws_s3_bucket` |",
+				"| `~` update | `aws_iam_role.app` | `aws_iam_role` |",
+				"| `+` create | `aws_instance.web` | `aws_instance` |",
+			}, "\n") + "\n"))
+		})
+
+		It("renders a plan without changes", func() {
+			Expect(jsonplan.Plan{}.Markdown()).To(ContainSubstring("No changes."))
+		})
+	})
+})
@@ -0,0 +1,46 @@
+package jsonplan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// most destructive changes first so they stand out to reviewers
+var markdownActionOrder = []string{ReplaceAction, DeleteAction, UpdateAction, CreateAction}
+
+// Markdown renders the plan as a table of changes grouped by action,
+// suitable for posting as a pull request comment.
+func (p Plan) Markdown() string {
+	summary := p.Summary()
+	if !summary.HasChanges() {
+		return "### Terraform Plan\n\nNo changes. Infrastructure is up-to-date.\n"
+	}
+
+	lines := []string{
+		"### Terraform Plan",
+		"",
+		fmt.Sprintf("**Plan:** %d to add, %d to change, %d to destroy, %d to replace.",
+			summary.Add, summary.Change, summary.Destroy, summary.Replace),
+		"",
+		"| Action | Resource | Type |",
+		"| --- | --- | --- |",
+	}
+
+	changes := p.managedChanges()
+	for _, action := range markdownActionOrder {
+		for _, change := range changes {
+			if change.Action() != action {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("| `%s` %s | `%s` | `%s` |",
+				actionSymbols[action], action, escapeTableCell(change.Address), change.Type))
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// for_each keys may contain pipes which would otherwise break the table
+func escapeTableCell(value string) string {
+	return strings.Replace(value, "|", "\\|", -1)
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	LowerCase = "lower"
+	UpperCase = "upper"
+)
+
+// EnvNameRules are enforced on every env name before any terraform command
+// runs, whether it was given as `env_name`, read from `env_name_file` or generated.
+type EnvNameRules struct {
+	AllowedPattern string   `json:"allowed_pattern,omitempty"` // optional
+	MaxLength      int      `json:"max_length,omitempty"`      // optional
+	Case           string   `json:"case,omitempty"`            // optional
+	ReservedNames  []string `json:"reserved_names,omitempty"`  // optional
+}
+
+func (r EnvNameRules) Validate() error {
+	if r.AllowedPattern != "" {
+		if _, err := regexp.Compile(r.AllowedPattern); err != nil {
+			return fmt.Errorf("Invalid `env_name_rules.allowed_pattern`: %s", err)
+		}
+	}
+	if r.MaxLength < 0 {
+		return fmt.Errorf("`env_name_rules.max_length` must not be negative")
+	}
+	switch r.Case {
+	case "", LowerCase, UpperCase:
+	default:
+		return fmt.Errorf("Unknown value for `env_name_rules.case`: '%s', Supported values: '%s', '%s'", r.Case, LowerCase, UpperCase)
+	}
+	for _, pattern := range r.ReservedNames {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid pattern '%s' in `env_name_rules.reserved_names`: %s", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Normalize folds the case of the env name and returns an error if the
+// result breaks any of the rules
+func (r EnvNameRules) Normalize(envName string) (string, error) {
+	switch r.Case {
+	case LowerCase:
+		envName = strings.ToLower(envName)
+	case UpperCase:
+		envName = strings.ToUpper(envName)
+	}
+
+	if r.AllowedPattern != "" {
+		allowed, err := regexp.MatchString(r.AllowedPattern, envName)
+		if err != nil {
+			return "", fmt.Errorf("Invalid `env_name_rules.allowed_pattern`: %s", err)
+		}
+		if !allowed {
+			return "", fmt.Errorf("Invalid env name '%s': must match `env_name_rules.allowed_pattern` '%s'", envName, r.AllowedPattern)
+		}
+	}
+
+	if r.MaxLength > 0 && len(envName) > r.MaxLength {
+		return "", fmt.Errorf("Invalid env name '%s': must not be longer than `env_name_rules.max_length` of %d characters", envName, r.MaxLength)
+	}
+
+	for _, pattern := range r.ReservedNames {
+		if reserved, _ := path.Match(pattern, envName); reserved {
+			return "", fmt.Errorf("Invalid env name '%s': matches '%s' in `env_name_rules.reserved_names`", envName, pattern)
+		}
+	}
+
+	return envName, nil
+}
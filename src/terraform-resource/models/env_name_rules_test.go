@@ -0,0 +1,86 @@
+package models_test
+
+import (
+	"github.com/ljfranklin/terraform-resource/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EnvNameRules", func() {
+
+	Describe("#Validate", func() {
+		It("returns nil for empty rules", func() {
+			Expect(models.EnvNameRules{}.Validate()).To(Succeed())
+		})
+
+		It("returns an error for an invalid `allowed_pattern`", func() {
+			rules := models.EnvNameRules{AllowedPattern: "^[a-z"}
+			Expect(rules.Validate()).To(MatchError(ContainSubstring("Invalid `env_name_rules.allowed_pattern`")))
+		})
+
+		It("returns an error for a negative `max_length`", func() {
+			rules := models.EnvNameRules{MaxLength: -1}
+			Expect(rules.Validate()).To(MatchError("`env_name_rules.max_length` must not be negative"))
+		})
+
+		It("returns an error for an unknown `case`", func() {
+			rules := models.EnvNameRules{Case: "title"}
+			Expect(rules.Validate()).To(MatchError("Unknown value for `env_name_rules.case`: 'title', Supported values: 'lower', 'upper'"))
+		})
+
+		It("returns an error for an invalid `reserved_names` pattern", func() {
+			rules := models.EnvNameRules{ReservedNames: []string{"prod-["}}
+			Expect(rules.Validate()).To(MatchError(ContainSubstring("Invalid pattern 'prod-[' in `env_name_rules.reserved_names`")))
+		})
+	})
+
+	Describe("#Normalize", func() {
+		var rules models.EnvNameRules
+
+		BeforeEach(func() {
+			rules = models.EnvNameRules{
+				AllowedPattern: "^[a-z0-9-]+$",
+				MaxLength:      12,
+				Case:           models.LowerCase,
+				ReservedNames:  []string{"default", "*-plan"},
+			}
+		})
+
+		It("returns the name unchanged without rules", func() {
+			envName, err := models.EnvNameRules{}.Normalize("Some/Env")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(envName).To(Equal("Some/Env"))
+		})
+
+		It("folds the case before checking the other rules", func() {
+			envName, err := rules.Normalize("Staging-A")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(envName).To(Equal("staging-a"))
+		})
+
+		It("folds to upper case", func() {
+			envName, err := models.EnvNameRules{Case: models.UpperCase}.Normalize("staging")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(envName).To(Equal("STAGING"))
+		})
+
+		It("returns an error if the name does not match `allowed_pattern`", func() {
+			_, err := rules.Normalize("team/staging")
+			Expect(err).To(MatchError("Invalid env name 'team/staging': must match `env_name_rules.allowed_pattern` '^[a-z0-9-]+$'"))
+		})
+
+		It("returns an error if the name is longer than `max_length`", func() {
+			_, err := rules.Normalize("staging-one-two")
+			Expect(err).To(MatchError("Invalid env name 'staging-one-two': must not be longer than `env_name_rules.max_length` of 12 characters"))
+		})
+
+		It("returns an error if the name is reserved", func() {
+			_, err := rules.Normalize("Default")
+			Expect(err).To(MatchError("Invalid env name 'default': matches 'default' in `env_name_rules.reserved_names`"))
+
+			_, err = rules.Normalize("staging-plan")
+			Expect(err).To(MatchError("Invalid env name 'staging-plan': matches '*-plan' in `env_name_rules.reserved_names`"))
+		})
+	})
+})
@@ -12,8 +12,21 @@ type InResponse struct {
 }
 
 type InParams struct {
-	Action             string `json:"action,omitempty"`           // optional
-	OutputStatefile    bool   `json:"output_statefile,omitempty"` // optional
-	OutputJSONPlanfile bool   `json:"output_planfile,omitempty"`  // optional
+	Action             string   `json:"action,omitempty"`              // optional
+	OutputStatefile    bool     `json:"output_statefile,omitempty"`    // optional
+	OutputJSONPlanfile bool     `json:"output_planfile,omitempty"`     // optional
+	OutputPlanText     bool     `json:"output_plan_text,omitempty"`    // optional
+	OutputPlanSummary  bool     `json:"output_plan_summary,omitempty"` // optional
+	OutputFormats      []string `json:"output_formats,omitempty"`      // optional
+	OutputFiles        bool     `json:"output_files,omitempty"`        // optional
+	SensitiveOutputs   string   `json:"sensitive_outputs,omitempty"`   // optional
+	ListEnvs           bool     `json:"list_envs,omitempty"`           // optional
 	Terraform
 }
+
+// Supported values for `sensitive_outputs`
+const (
+	IncludeSensitiveOutputs  = "include"
+	SeparateSensitiveOutputs = "separate"
+	OmitSensitiveOutputs     = "omit"
+)
@@ -11,13 +11,32 @@ type OutResponse struct {
 }
 
 type OutParams struct {
-	EnvName            string `json:"env_name"`
-	EnvNameFile        string `json:"env_name_file"`
-	GenerateRandomName bool   `json:"generate_random_name"`
-	Action             string `json:"action,omitempty"` // optional
+	EnvName            string            `json:"env_name"`
+	EnvNameFile        string            `json:"env_name_file"`
+	GenerateRandomName bool              `json:"generate_random_name"`
+	Action             string            `json:"action,omitempty"`             // optional
+	PlanChecksumFile   string            `json:"plan_checksum_file,omitempty"` // optional
+	EnvNameTemplate    string            `json:"env_name_template,omitempty"`  // optional
+	EnvNameWords       []string          `json:"env_name_words,omitempty"`     // optional
+	TTL                string            `json:"ttl,omitempty"`                // optional
+	Labels             map[string]string `json:"labels,omitempty"`             // optional
+	MaxReap            int               `json:"max_reap,omitempty"`           // optional
+	DryRun             bool              `json:"dry_run,omitempty"`            // optional
 	Terraform
 }
 
+// GeneratesEnvName returns true if the env name is generated by a namer
+func (p OutParams) GeneratesEnvName() bool {
+	return p.GenerateRandomName || p.EnvNameTemplate != ""
+}
+
+// RecordsEnvMetadata returns true if `put` updates the `<env>-meta` workspace
+func (p OutParams) RecordsEnvMetadata() bool {
+	return p.TTL != "" || p.Labels != nil
+}
+
 const (
 	DestroyAction = "destroy"
+	RefreshAction = "refresh"
+	ReapAction    = "reap"
 )
@@ -2,6 +2,9 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+
 	"github.com/ljfranklin/terraform-resource/storage"
 )
 
@@ -10,6 +13,9 @@ type Source struct {
 	Storage             storage.Model `json:"storage,omitempty"`               // optional
 	MigratedFromStorage storage.Model `json:"migrated_from_storage,omitempty"` // optional
 	EnvName             string        `json:"env_name,omitempty"`              // optional
+	DetectDrift         bool          `json:"detect_drift,omitempty"`          // optional
+	CheckEnvPattern     string        `json:"check_env_pattern,omitempty"`     // optional
+	EnvNameRules        EnvNameRules  `json:"env_name_rules,omitempty"`        // optional
 }
 
 func (s Source) Validate() error {
@@ -25,6 +31,41 @@ func (s Source) Validate() error {
 		return errors.New("Must specify `backend_type` and `backend_config` when using `migrated_from_storage`.")
 	}
 
+	if s.DetectDrift && s.Terraform.BackendType == "" {
+		return errors.New("Must specify `backend_type` and `backend_config` when using `detect_drift`.")
+	}
+
+	if s.DetectDrift && s.Terraform.Source == "" {
+		return errors.New("Must specify `terraform_source` as a module address, e.g. `git::https://example.com/infra.git//terraform`, when using `detect_drift`.")
+	}
+
+	if s.Terraform.WorkspacePrefix != "" && s.Terraform.BackendType == "" {
+		return errors.New("Must specify `backend_type` and `backend_config` when using `workspace_prefix`.")
+	}
+
+	if s.CheckEnvPattern != "" {
+		if s.Terraform.BackendType == "" {
+			return errors.New("Must specify `backend_type` and `backend_config` when using `check_env_pattern`.")
+		}
+		if s.EnvName != "" {
+			return errors.New("Cannot specify both `env_name` and `check_env_pattern`.")
+		}
+		if s.DetectDrift {
+			return errors.New("Cannot specify both `detect_drift` and `check_env_pattern`.")
+		}
+		if _, err := regexp.Compile(s.CheckEnvPattern); err != nil {
+			return fmt.Errorf("Invalid `check_env_pattern`: %s", err)
+		}
+	}
+
+	if s.Terraform.UsesPlanStorage() && s.Terraform.BackendType == "" {
+		return errors.New("Must specify `backend_type` and `backend_config` when using `plan_storage`.")
+	}
+
+	if err := s.EnvNameRules.Validate(); err != nil {
+		return err
+	}
+
 	if err := s.Terraform.Validate(); err != nil {
 		return err
 	}
@@ -39,6 +39,35 @@ var _ = Describe("Source Model", func() {
 				BackendConfig: map[string]interface{}{"some-key": "some-value"},
 			},
 		}),
+		Entry("Backend with drift detection", models.Source{
+			EnvName:     "some-env",
+			DetectDrift: true,
+			Terraform: models.Terraform{
+				Source:        "git::https://example.com/some-repo.git//terraform",
+				BackendType:   "some-backend",
+				BackendConfig: map[string]interface{}{"some-key": "some-value"},
+			},
+		}),
+		Entry("Backend with plan storage", models.Source{
+			EnvName: "some-env",
+			Terraform: models.Terraform{
+				Source:        "some-source",
+				BackendType:   "some-backend",
+				BackendConfig: map[string]interface{}{"some-key": "some-value"},
+				PlanStorage: storage.Model{
+					Driver:    storage.FSDriver,
+					Directory: "/tmp/some-plans",
+				},
+			},
+		}),
+		Entry("Backend with check env pattern", models.Source{
+			CheckEnvPattern: "^pr-",
+			Terraform: models.Terraform{
+				Source:        "some-source",
+				BackendType:   "some-backend",
+				BackendConfig: map[string]interface{}{"some-key": "some-value"},
+			},
+		}),
 		Entry("Legacy Storage", models.Source{
 			EnvName: "some-env",
 			Storage: storage.Model{
@@ -108,6 +137,106 @@ var _ = Describe("Source Model", func() {
 				Source: "some-source",
 			},
 		}, "Cannot specify both `migrated_from_storage` and `storage`"),
+		Entry("Drift detection without Backend", models.Source{
+			EnvName:     "some-env",
+			DetectDrift: true,
+			Storage: storage.Model{
+				Driver:          "s3",
+				Bucket:          "some-bucket",
+				BucketPath:      "some-path",
+				AccessKeyID:     "some-key",
+				SecretAccessKey: "some-secret",
+			},
+			Terraform: models.Terraform{
+				Source: "some-source",
+			},
+		}, "Must specify `backend_type` and `backend_config` when using `detect_drift`"),
+		Entry("Drift detection without terraform_source", models.Source{
+			EnvName:     "some-env",
+			DetectDrift: true,
+			Terraform: models.Terraform{
+				BackendType:   "some-backend",
+				BackendConfig: map[string]interface{}{"some-key": "some-value"},
+			},
+		}, "Must specify `terraform_source` as a module address"),
+		Entry("Plan storage without Backend", models.Source{
+			EnvName: "some-env",
+			Storage: storage.Model{
+				Driver:          "s3",
+				Bucket:          "some-bucket",
+				BucketPath:      "some-path",
+				AccessKeyID:     "some-key",
+				SecretAccessKey: "some-secret",
+			},
+			Terraform: models.Terraform{
+				Source: "some-source",
+				PlanStorage: storage.Model{
+					Driver:    storage.FSDriver,
+					Directory: "/tmp/some-plans",
+				},
+			},
+		}, "Must specify `backend_type` and `backend_config` when using `plan_storage`"),
+		Entry("Invalid plan storage", models.Source{
+			EnvName: "some-env",
+			Terraform: models.Terraform{
+				Source:        "some-source",
+				BackendType:   "some-backend",
+				BackendConfig: map[string]interface{}{"some-key": "some-value"},
+				PlanStorage: storage.Model{
+					Driver: storage.FSDriver,
+				},
+			},
+		}, "Invalid `plan_storage`: Missing fields: 'storage.directory'"),
+		Entry("Workspace prefix without Backend", models.Source{
+			EnvName: "some-env",
+			Terraform: models.Terraform{
+				Source:          "some-source",
+				WorkspacePrefix: "some-prefix-",
+			},
+		}, "Must specify `backend_type` and `backend_config` when using `workspace_prefix`"),
+		Entry("Check env pattern without Backend", models.Source{
+			CheckEnvPattern: "^pr-",
+			Terraform: models.Terraform{
+				Source: "some-source",
+			},
+		}, "Must specify `backend_type` and `backend_config` when using `check_env_pattern`"),
+		Entry("Check env pattern and env_name", models.Source{
+			EnvName:         "some-env",
+			CheckEnvPattern: "^pr-",
+			Terraform: models.Terraform{
+				Source:        "some-source",
+				BackendType:   "some-backend",
+				BackendConfig: map[string]interface{}{"some-key": "some-value"},
+			},
+		}, "Cannot specify both `env_name` and `check_env_pattern`"),
+		Entry("Check env pattern and drift detection", models.Source{
+			CheckEnvPattern: "^pr-",
+			DetectDrift:     true,
+			Terraform: models.Terraform{
+				Source:        "git::https://example.com/some-repo.git//terraform",
+				BackendType:   "some-backend",
+				BackendConfig: map[string]interface{}{"some-key": "some-value"},
+			},
+		}, "Cannot specify both `detect_drift` and `check_env_pattern`"),
+		Entry("Invalid check env pattern", models.Source{
+			CheckEnvPattern: "pr-(",
+			Terraform: models.Terraform{
+				Source:        "some-source",
+				BackendType:   "some-backend",
+				BackendConfig: map[string]interface{}{"some-key": "some-value"},
+			},
+		}, "Invalid `check_env_pattern`"),
+		Entry("Invalid env name rules", models.Source{
+			EnvName: "some-env",
+			EnvNameRules: models.EnvNameRules{
+				Case: "title",
+			},
+			Terraform: models.Terraform{
+				Source:        "some-source",
+				BackendType:   "some-backend",
+				BackendConfig: map[string]interface{}{"some-key": "some-value"},
+			},
+		}, "Unknown value for `env_name_rules.case`"),
 		Entry("Unknown Legacy Storage driver", models.Source{
 			EnvName: "some-env",
 			Storage: storage.Model{
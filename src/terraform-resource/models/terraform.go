@@ -1,11 +1,15 @@
 package models
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"path"
 	"strings"
 
 	yamlConverter "github.com/ghodss/yaml"
+	"github.com/ljfranklin/terraform-resource/storage"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -17,6 +21,7 @@ type Terraform struct {
 	DeleteOnFailure       bool                   `json:"delete_on_failure,omitempty"`     // optional
 	PlanOnly              bool                   `json:"plan_only,omitempty"`             // optional
 	PlanRun               bool                   `json:"plan_run,omitempty"`              // optional
+	AllowUnverifiedPlan   bool                   `json:"allow_unverified_plan,omitempty"` // optional
 	OutputModule          string                 `json:"output_module,omitempty"`         // optional
 	ImportFiles           []string               `json:"import_files,omitempty"`          // optional
 	OverrideFiles         []string               `json:"override_files,omitempty"`        // optional
@@ -26,9 +31,24 @@ type Terraform struct {
 	BackendConfig         map[string]interface{} `json:"backend_config,omitempty"`        // optional
 	Parallelism           int                    `json:"parallelism,omitempty"`           // optional
 	LockTimeout           string                 `json:"lock_timeout,omitempty"`          // optional
+	Targets               []string               `json:"targets,omitempty"`               // optional
+	Replace               []string               `json:"replace,omitempty"`               // optional
+	PolicyFile            string                 `json:"policy_file,omitempty"`           // optional
+	MaxDestroyCount       *int                   `json:"max_destroy_count,omitempty"`     // optional
+	MaxReplaceCount       *int                   `json:"max_replace_count,omitempty"`     // optional
+	PlanMaxAge            string                 `json:"plan_max_age,omitempty"`          // optional
+	PlanName              string                 `json:"plan_name,omitempty"`             // optional
+	CloneFromEnv          string                 `json:"clone_from_env,omitempty"`        // optional
+	PlanEncryptionKey     string                 `json:"plan_encryption_key,omitempty"`   // optional
+	PlanStorage           storage.Model          `json:"plan_storage,omitempty"`          // optional
+	MetadataOutputs       []string               `json:"metadata_outputs,omitempty"`      // optional
+	MetadataMaxLength     int                    `json:"metadata_max_length,omitempty"`   // optional
+	MetadataFlatten       bool                   `json:"metadata_flatten,omitempty"`      // optional
+	WorkspacePrefix       string                 `json:"workspace_prefix,omitempty"`      // optional, only allowed in `source`
 	PrivateKey            string                 `json:"private_key,omitempty"`
 	PlanFileLocalPath     string                 `json:"-"` // not specified pipeline
 	JSONPlanFileLocalPath string                 `json:"-"` // not specified pipeline
+	PlanChecksum          string                 `json:"-"` // not specified pipeline
 	PlanFileRemotePath    string                 `json:"-"` // not specified pipeline
 	StateFileLocalPath    string                 `json:"-"` // not specified pipeline
 	StateFileRemotePath   string                 `json:"-"` // not specified pipeline
@@ -40,12 +60,67 @@ type Terraform struct {
 const (
 	PlanContent     = "plan_content"
 	PlanContentJSON = "plan_content_json"
+	PlanContentText = "plan_content_text"
+	PlanStateSerial = "plan_state_serial"
+	PlanLineage     = "plan_state_lineage"
+	PlanCreatedAt   = "plan_created_at"
 )
 
 func (m Terraform) Validate() error {
+	if m.PlanEncryptionKey != "" {
+		if _, err := DecodePlanEncryptionKey(m.PlanEncryptionKey); err != nil {
+			return err
+		}
+	}
+	if m.UsesPlanStorage() {
+		if err := m.PlanStorage.Validate(); err != nil {
+			return fmt.Errorf("Invalid `plan_storage`: %s", err)
+		}
+	}
+	for _, pattern := range m.MetadataOutputs {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid pattern '%s' in `metadata_outputs`: %s", pattern, err)
+		}
+	}
+	if m.MetadataMaxLength < 0 {
+		return errors.New("`metadata_max_length` must not be negative")
+	}
 	return nil
 }
 
+// ShowsOutputInMetadata returns true if the output matches `metadata_outputs`,
+// all outputs are shown if no patterns are given
+func (m Terraform) ShowsOutputInMetadata(outputName string) bool {
+	if len(m.MetadataOutputs) == 0 {
+		return true
+	}
+	for _, pattern := range m.MetadataOutputs {
+		if matched, _ := path.Match(pattern, outputName); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkspaceName returns the Terraform workspace which stores the given env
+func (m Terraform) WorkspaceName(envName string) string {
+	return m.WorkspacePrefix + envName
+}
+
+// UsesPlanStorage returns true if plans are saved to `plan_storage` rather than to a plan workspace
+func (m Terraform) UsesPlanStorage() bool {
+	return m.PlanStorage != (storage.Model{})
+}
+
+// DecodePlanEncryptionKey returns the AES-256 key given as `plan_encryption_key`
+func DecodePlanEncryptionKey(encodedKey string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("`plan_encryption_key` must be a base64 encoded 32 byte key, e.g. generated with `openssl rand -base64 32`")
+	}
+	return key, nil
+}
+
 func (m Terraform) Merge(other Terraform) Terraform {
 	mergedVars := map[string]interface{}{}
 	for key, value := range m.Vars {
@@ -81,6 +156,10 @@ func (m Terraform) Merge(other Terraform) Terraform {
 		m.JSONPlanFileLocalPath = other.JSONPlanFileLocalPath
 	}
 
+	if other.PlanChecksum != "" {
+		m.PlanChecksum = other.PlanChecksum
+	}
+
 	if other.PlanFileRemotePath != "" {
 		m.PlanFileRemotePath = other.PlanFileRemotePath
 	}
@@ -97,6 +176,26 @@ func (m Terraform) Merge(other Terraform) Terraform {
 		m.PrivateKey = other.PrivateKey
 	}
 
+	if other.PlanEncryptionKey != "" {
+		m.PlanEncryptionKey = other.PlanEncryptionKey
+	}
+
+	if other.UsesPlanStorage() {
+		m.PlanStorage = other.PlanStorage
+	}
+
+	if len(other.MetadataOutputs) > 0 {
+		m.MetadataOutputs = other.MetadataOutputs
+	}
+
+	if other.MetadataMaxLength != 0 {
+		m.MetadataMaxLength = other.MetadataMaxLength
+	}
+
+	if other.MetadataFlatten {
+		m.MetadataFlatten = true
+	}
+
 	if other.PlanOnly {
 		m.PlanOnly = true
 	}
@@ -109,6 +208,10 @@ func (m Terraform) Merge(other Terraform) Terraform {
 		m.PlanRun = true
 	}
 
+	if other.AllowUnverifiedPlan {
+		m.AllowUnverifiedPlan = true
+	}
+
 	if other.DeleteOnFailure {
 		m.DeleteOnFailure = true
 	}
@@ -145,6 +248,39 @@ func (m Terraform) Merge(other Terraform) Terraform {
 		m.Parallelism = other.Parallelism
 	}
 
+	if other.Targets != nil {
+		m.Targets = other.Targets
+	}
+
+	if other.Replace != nil {
+		m.Replace = other.Replace
+	}
+
+	if other.PolicyFile != "" {
+		m.PolicyFile = other.PolicyFile
+	}
+
+	if other.PlanName != "" {
+		m.PlanName = other.PlanName
+	}
+
+	if other.CloneFromEnv != "" {
+		m.CloneFromEnv = other.CloneFromEnv
+	}
+
+	if other.PlanMaxAge != "" {
+		m.PlanMaxAge = other.PlanMaxAge
+	}
+
+	// pointers since a threshold of 0 is meaningful
+	if other.MaxDestroyCount != nil {
+		m.MaxDestroyCount = other.MaxDestroyCount
+	}
+
+	if other.MaxReplaceCount != nil {
+		m.MaxReplaceCount = other.MaxReplaceCount
+	}
+
 	return m
 }
 
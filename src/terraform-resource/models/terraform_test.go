@@ -7,6 +7,7 @@ import (
 	"path"
 
 	"github.com/ljfranklin/terraform-resource/models"
+	"github.com/ljfranklin/terraform-resource/storage"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -48,7 +49,52 @@ var _ = Describe("Terraform Models", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("returns an error if `plan_encryption_key` is not a 32 byte key", func() {
+			model := models.Terraform{
+				Source:            "fake-source",
+				PlanEncryptionKey: "dG9vLXNob3J0",
+			}
+
+			err := model.Validate()
+			Expect(err).To(MatchError(ContainSubstring("`plan_encryption_key` must be a base64 encoded 32 byte key")))
+		})
+
+		It("returns an error for invalid `metadata_outputs` patterns", func() {
+			model := models.Terraform{
+				Source:          "fake-source",
+				MetadataOutputs: []string{"vpc_["},
+			}
+
+			err := model.Validate()
+			Expect(err).To(MatchError(ContainSubstring("Invalid pattern 'vpc_[' in `metadata_outputs`")))
+		})
+
+		It("returns an error for a negative `metadata_max_length`", func() {
+			model := models.Terraform{
+				Source:            "fake-source",
+				MetadataMaxLength: -1,
+			}
+
+			err := model.Validate()
+			Expect(err).To(MatchError("`metadata_max_length` must not be negative"))
+		})
+
+		It("only takes `workspace_prefix` from the original model", func() {
+			baseModel := models.Terraform{
+				WorkspacePrefix: "team-a-",
+			}
+			mergeModel := models.Terraform{
+				WorkspacePrefix: "team-b-",
+			}
+
+			finalModel := baseModel.Merge(mergeModel)
+			Expect(finalModel.WorkspacePrefix).To(Equal("team-a-"))
+			Expect(finalModel.WorkspaceName("staging")).To(Equal("team-a-staging"))
+		})
+
 		It("merges non-var fields", func() {
+			maxDestroyCount := 0
+			maxReplaceCount := 2
 			baseModel := models.Terraform{
 				Source: "base-source",
 			}
@@ -63,6 +109,16 @@ var _ = Describe("Terraform Models", func() {
 				PluginDir:           "fake-plugin-path",
 				BackendType:         "fake-type",
 				BackendConfig:       map[string]interface{}{"fake-backend-key": "fake-backend-value"},
+				Targets:             []string{"fake-target"},
+				Replace:             []string{"fake-replace"},
+				MaxDestroyCount:     &maxDestroyCount,
+				MaxReplaceCount:     &maxReplaceCount,
+				PlanMaxAge:          "24h",
+				AllowUnverifiedPlan: true,
+				PlanStorage:         storage.Model{Driver: storage.FSDriver, Directory: "fake-plan-dir"},
+				MetadataOutputs:     []string{"fake-output-*"},
+				MetadataMaxLength:   100,
+				MetadataFlatten:     true,
 			}
 
 			finalModel := baseModel.Merge(mergeModel)
@@ -77,6 +133,16 @@ var _ = Describe("Terraform Models", func() {
 			Expect(finalModel.PluginDir).To(Equal("fake-plugin-path"))
 			Expect(finalModel.BackendType).To(Equal("fake-type"))
 			Expect(finalModel.BackendConfig).To(Equal(map[string]interface{}{"fake-backend-key": "fake-backend-value"}))
+			Expect(finalModel.Targets).To(Equal([]string{"fake-target"}))
+			Expect(finalModel.Replace).To(Equal([]string{"fake-replace"}))
+			Expect(*finalModel.MaxDestroyCount).To(Equal(0))
+			Expect(*finalModel.MaxReplaceCount).To(Equal(2))
+			Expect(finalModel.PlanMaxAge).To(Equal("24h"))
+			Expect(finalModel.AllowUnverifiedPlan).To(BeTrue())
+			Expect(finalModel.PlanStorage).To(Equal(storage.Model{Driver: storage.FSDriver, Directory: "fake-plan-dir"}))
+			Expect(finalModel.MetadataOutputs).To(Equal([]string{"fake-output-*"}))
+			Expect(finalModel.MetadataMaxLength).To(Equal(100))
+			Expect(finalModel.MetadataFlatten).To(BeTrue())
 		})
 	})
 
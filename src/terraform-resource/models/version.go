@@ -15,12 +15,18 @@ const (
 )
 
 type Version struct {
-	Serial       string `json:"serial"`
-	EnvName      string `json:"env_name"`
-	Lineage      string `json:"lineage,omitempty"`       // omitted on older version
-	LastModified string `json:"last_modified,omitempty"` // optional
-	PlanOnly     string `json:"plan_only,omitempty"`     //optional
-	PlanChecksum string `json:"plan_checksum,omitempty"` //optional
+	Serial        string `json:"serial"`
+	EnvName       string `json:"env_name"`
+	Lineage       string `json:"lineage,omitempty"`        // omitted on older version
+	LastModified  string `json:"last_modified,omitempty"`  // optional
+	PlanOnly      string `json:"plan_only,omitempty"`      //optional
+	PlanChecksum  string `json:"plan_checksum,omitempty"`  //optional
+	Drift         string `json:"drift,omitempty"`          //optional
+	DriftChecksum string `json:"drift_checksum,omitempty"` //optional
+	HasChanges    string `json:"has_changes,omitempty"`    //optional
+	PlanName      string `json:"plan_name,omitempty"`      //optional
+	Reap          string `json:"reap,omitempty"`           //optional
+	CheckedEnvs   string `json:"checked_envs,omitempty"`   //optional
 }
 
 func NewVersionFromLegacyStorage(storageVersion storage.Version) Version {
@@ -66,6 +72,12 @@ func (r Version) IsPlan() bool {
 	return r.PlanOnly == "true"
 }
 
+// IsReap reports whether the version was emitted by `action: reap`, which
+// touches many envs and so has no state of its own to fetch
+func (r Version) IsReap() bool {
+	return r.Reap == "true"
+}
+
 func (r Version) LastModifiedTime() time.Time {
 	// assumes Validate has already been called
 	lastModified, _ := time.Parse(TimeFormat, r.LastModified)
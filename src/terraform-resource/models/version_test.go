@@ -72,6 +72,13 @@ var _ = Describe("Version", func() {
 		})
 	})
 
+	Describe("#IsReap", func() {
+		It("returns true only for versions emitted by `action: reap`", func() {
+			Expect(models.Version{EnvName: "reap", Reap: "true"}.IsReap()).To(BeTrue())
+			Expect(models.Version{EnvName: "reap", Serial: "1"}.IsReap()).To(BeFalse())
+		})
+	})
+
 	Describe("#LastModifiedTime", func() {
 		It("returns the LastModified value as a Time struct", func() {
 			now := time.Now()
@@ -0,0 +1,122 @@
+package namer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Pallinder/go-randomdata"
+)
+
+// BuildMetadata is the Concourse build metadata available to name templates
+type BuildMetadata struct {
+	ID           string
+	Name         string
+	JobName      string
+	PipelineName string
+	TeamName     string
+}
+
+// BuildMetadataFromEnv reads the build metadata Concourse passes to `put`
+func BuildMetadataFromEnv() BuildMetadata {
+	return BuildMetadata{
+		ID:           os.Getenv("BUILD_ID"),
+		Name:         os.Getenv("BUILD_NAME"),
+		JobName:      os.Getenv("BUILD_JOB_NAME"),
+		PipelineName: os.Getenv("BUILD_PIPELINE_NAME"),
+		TeamName:     os.Getenv("BUILD_TEAM_NAME"),
+	}
+}
+
+// NewFromTemplate returns a Namer which renders the given text/template,
+// e.g. `{{.PipelineName}}-{{.Random 4}}-{{.Date}}`. The template is rendered
+// once up front so that mistakes are reported before any name is generated.
+func NewFromTemplate(text string, words []string, build BuildMetadata) (Namer, error) {
+	tmpl, err := template.New("env_name_template").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse `env_name_template`: %s", err)
+	}
+
+	n := &templateNamer{
+		template: tmpl,
+		words:    words,
+		build:    build,
+	}
+
+	name, err := n.render(templateData{namer: n, counter: 1})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to render `env_name_template`: %s", err)
+	}
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("`env_name_template` must not render an empty name")
+	}
+
+	return n, nil
+}
+
+type templateNamer struct {
+	template *template.Template
+	words    []string
+	build    BuildMetadata
+	counter  int
+}
+
+// RandomName renders the template, `.Counter` increases with every call so
+// that retries after a name clash produce a new name
+func (n *templateNamer) RandomName() string {
+	n.counter++
+	name, err := n.render(templateData{namer: n, counter: n.counter})
+	if err != nil {
+		// the template already rendered successfully in NewFromTemplate
+		return ""
+	}
+	return name
+}
+
+func (n *templateNamer) render(data templateData) (string, error) {
+	var buf bytes.Buffer
+	if err := n.template.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+type templateData struct {
+	namer   *templateNamer
+	counter int
+}
+
+func (d templateData) BuildID() string      { return d.namer.build.ID }
+func (d templateData) BuildName() string    { return d.namer.build.Name }
+func (d templateData) JobName() string      { return d.namer.build.JobName }
+func (d templateData) PipelineName() string { return d.namer.build.PipelineName }
+func (d templateData) TeamName() string     { return d.namer.build.TeamName }
+
+// Counter starts at 1 and increases each time a name is generated
+func (d templateData) Counter() int { return d.counter }
+
+// Date returns the current UTC date as YYYYMMDD
+func (d templateData) Date() string { return time.Now().UTC().Format("20060102") }
+
+func (d templateData) Adjective() string { return randomdata.Adjective() }
+func (d templateData) Noun() string      { return randomdata.Noun() }
+
+// Random returns n random lowercase letters and digits
+func (d templateData) Random(n int) (string, error) {
+	if n < 1 {
+		return "", fmt.Errorf("Random expects a positive length but got %d", n)
+	}
+	return strings.ToLower(randomdata.Alphanumeric(n)), nil
+}
+
+// Word returns a random entry of `env_name_words`
+func (d templateData) Word() (string, error) {
+	if len(d.namer.words) == 0 {
+		return "", errors.New("Word requires `env_name_words` to be set")
+	}
+	return randomdata.StringSample(d.namer.words...), nil
+}
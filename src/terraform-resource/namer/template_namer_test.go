@@ -0,0 +1,80 @@
+package namer_test
+
+import (
+	"time"
+
+	"github.com/ljfranklin/terraform-resource/namer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TemplateNamer", func() {
+
+	var build namer.BuildMetadata
+
+	BeforeEach(func() {
+		build = namer.BuildMetadata{
+			ID:           "42",
+			Name:         "7",
+			JobName:      "create-env",
+			PipelineName: "payments",
+			TeamName:     "main",
+		}
+	})
+
+	It("renders build metadata, random characters and the date", func() {
+		generator, err := namer.NewFromTemplate("{{.PipelineName}}-{{.Random 4}}-{{.Date}}", nil, build)
+		Expect(err).ToNot(HaveOccurred())
+
+		date := time.Now().UTC().Format("20060102")
+		Expect(generator.RandomName()).To(MatchRegexp("^payments-[a-z0-9]{4}-%s$", date))
+	})
+
+	It("renders the other build metadata fields", func() {
+		generator, err := namer.NewFromTemplate("{{.TeamName}}-{{.JobName}}-{{.BuildName}}-{{.BuildID}}", nil, build)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(generator.RandomName()).To(Equal("main-create-env-7-42"))
+	})
+
+	It("increases the counter with each generated name", func() {
+		generator, err := namer.NewFromTemplate("env-{{.Counter}}", nil, build)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(generator.RandomName()).To(Equal("env-1"))
+		Expect(generator.RandomName()).To(Equal("env-2"))
+		Expect(generator.RandomName()).To(Equal("env-3"))
+	})
+
+	It("picks words from the custom word list", func() {
+		generator, err := namer.NewFromTemplate("{{.Word}}-{{.Noun}}", []string{"red", "blue"}, build)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(generator.RandomName()).To(MatchRegexp("^(red|blue)-[a-z]+$"))
+	})
+
+	It("returns an error if the template cannot be parsed", func() {
+		_, err := namer.NewFromTemplate("{{.PipelineName", nil, build)
+		Expect(err).To(MatchError(ContainSubstring("Failed to parse `env_name_template`")))
+	})
+
+	It("returns an error if the template cannot be rendered", func() {
+		_, err := namer.NewFromTemplate("{{.Unknown}}", nil, build)
+		Expect(err).To(MatchError(ContainSubstring("Failed to render `env_name_template`")))
+
+		_, err = namer.NewFromTemplate("{{.Random 0}}", nil, build)
+		Expect(err).To(MatchError(ContainSubstring("Random expects a positive length but got 0")))
+	})
+
+	It("returns an error if `.Word` is used without a word list", func() {
+		_, err := namer.NewFromTemplate("{{.Word}}", nil, build)
+		Expect(err).To(MatchError(ContainSubstring("Word requires `env_name_words` to be set")))
+	})
+
+	It("returns an error if the template renders an empty name", func() {
+		build.PipelineName = ""
+		_, err := namer.NewFromTemplate(" {{.PipelineName}} ", nil, build)
+		Expect(err).To(MatchError("`env_name_template` must not render an empty name"))
+	})
+})
@@ -30,7 +30,7 @@ func (b BackendEnvNamer) EnvName() (string, error) {
 			return "", fmt.Errorf("Failed to read `env_name_file`: %s", err)
 		}
 		envName = string(contents)
-	} else if params.GenerateRandomName {
+	} else if params.GeneratesEnvName() {
 		var err error
 		envName, err = b.generateRandomName()
 		if err != nil {
@@ -43,12 +43,12 @@ func (b BackendEnvNamer) EnvName() (string, error) {
 	}
 
 	if len(envName) == 0 {
-		return "", fmt.Errorf("Must specify `put.params.env_name`, `put.params.env_name_file`, `put.params.generate_random_name`, or `source.env_name`")
+		return "", fmt.Errorf("Must specify `put.params.env_name`, `put.params.env_name_file`, `put.params.generate_random_name`, `put.params.env_name_template`, or `source.env_name`")
 	}
 	envName = strings.TrimSpace(envName)
 	envName = strings.Replace(envName, " ", "-", -1)
 
-	return envName, nil
+	return b.Req.Source.EnvNameRules.Normalize(envName)
 }
 
 func (b BackendEnvNamer) generateRandomName() (string, error) {
@@ -63,14 +63,27 @@ func (b BackendEnvNamer) generateRandomName() (string, error) {
 
 	var envName string
 	for i := 0; i < NameClashRetries; i++ {
-		randomName := b.Namer.RandomName()
+		randomName, err := b.Req.Source.EnvNameRules.Normalize(b.Namer.RandomName())
+		if err != nil {
+			return "", fmt.Errorf("Generated name does not follow `env_name_rules`: %s", err)
+		}
 		clash := false
 		for _, e := range existingEnvs {
-			if e == randomName {
+			if e == b.Req.Source.Terraform.WorkspaceName(randomName) {
 				clash = true
 				break
 			}
 		}
+		// the plans of a released name still refer to it
+		if len(terraform.PendingPlanNames(existingEnvs, b.Req.Source.Terraform.WorkspaceName(randomName))) > 0 {
+			clash = true
+		}
+		if clash == false {
+			clash, err = reserveWorkspace(b.TerraformClient, b.Req.Source.Terraform.WorkspaceName(randomName))
+			if err != nil {
+				return "", err
+			}
+		}
 		if clash == false {
 			envName = randomName
 			break
@@ -93,7 +106,7 @@ type MigratedFromStorageEnvNamer struct {
 func (m MigratedFromStorageEnvNamer) EnvName() (string, error) {
 	params := m.Req.Params
 
-	if params.GenerateRandomName {
+	if params.GeneratesEnvName() {
 		return m.generateRandomName()
 	}
 
@@ -116,20 +129,33 @@ func (m MigratedFromStorageEnvNamer) generateRandomName() (string, error) {
 
 	var envName string
 	for i := 0; i < NameClashRetries; i++ {
-		randomName := m.Namer.RandomName()
+		randomName, err := m.Req.Source.EnvNameRules.Normalize(m.Namer.RandomName())
+		if err != nil {
+			return "", fmt.Errorf("Generated name does not follow `env_name_rules`: %s", err)
+		}
 		clash := false
 		for _, e := range existingEnvs {
-			if e == randomName {
+			if e == m.Req.Source.Terraform.WorkspaceName(randomName) {
 				clash = true
 				break
 			}
 		}
+		// the plans of a released name still refer to it
+		if len(terraform.PendingPlanNames(existingEnvs, m.Req.Source.Terraform.WorkspaceName(randomName))) > 0 {
+			clash = true
+		}
 		if clash == false {
 			clash, err = doesEnvNameClashWithLegacyEnv(randomName, m.StorageDriver)
 			if err != nil {
 				return "", err
 			}
 		}
+		if clash == false {
+			clash, err = reserveWorkspace(m.TerraformClient, m.Req.Source.Terraform.WorkspaceName(randomName))
+			if err != nil {
+				return "", err
+			}
+		}
 		if clash == false {
 			envName = randomName
 			break
@@ -159,10 +185,12 @@ func (l LegacyStorageEnvNamer) EnvName() (string, error) {
 		envName = string(contents)
 	} else if len(params.EnvName) > 0 {
 		envName = params.EnvName
-	} else if params.GenerateRandomName {
-		var randomName string
+	} else if params.GeneratesEnvName() {
 		for i := 0; i < NameClashRetries; i++ {
-			randomName = l.Namer.RandomName()
+			randomName, err := l.Req.Source.EnvNameRules.Normalize(l.Namer.RandomName())
+			if err != nil {
+				return "", fmt.Errorf("Generated name does not follow `env_name_rules`: %s", err)
+			}
 			clash, err := doesEnvNameClashWithLegacyEnv(randomName, l.StorageDriver)
 			if err != nil {
 				return "", err
@@ -180,12 +208,62 @@ func (l LegacyStorageEnvNamer) EnvName() (string, error) {
 	}
 
 	if len(envName) == 0 {
-		return "", fmt.Errorf("Must specify `put.params.env_name`, `put.params.env_name_file`, `put.params.generate_random_name`, or `source.env_name`")
+		return "", fmt.Errorf("Must specify `put.params.env_name`, `put.params.env_name_file`, `put.params.generate_random_name`, `put.params.env_name_template`, or `source.env_name`")
 	}
 	envName = strings.TrimSpace(envName)
 	envName = strings.Replace(envName, " ", "-", -1)
 
-	return envName, nil
+	return l.Req.Source.EnvNameRules.Normalize(envName)
+}
+
+// reserveWorkspace creates the workspace right away so that concurrent builds
+// generating the same name notice the clash, rather than both applying to it
+func reserveWorkspace(client terraform.Client, workspace string) (bool, error) {
+	err := client.WorkspaceNew(workspace)
+	if err == terraform.ErrWorkspaceExists {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Failed to reserve workspace '%s': %s", workspace, err)
+	}
+	return false, nil
+}
+
+// ReleaseEnvName deletes the workspace reserved for a generated name after a
+// `plan_only` or failed put, so unused names don't pile up in the backend.
+// A workspace which holds resources, e.g. from a partially failed apply, is kept.
+func (b BackendEnvNamer) ReleaseEnvName(envName string) error {
+	workspace := b.Req.Source.Terraform.WorkspaceName(envName)
+
+	spaces, err := b.TerraformClient.WorkspaceList()
+	if err != nil {
+		return err
+	}
+	exists := false
+	for _, space := range spaces {
+		if space == workspace {
+			exists = true
+			break
+		}
+	}
+	if !exists {
+		// e.g. already removed by `delete_on_failure`
+		return nil
+	}
+
+	rawState, err := b.TerraformClient.StatePull(workspace)
+	if err != nil {
+		return err
+	}
+	resourceCount, err := terraform.StateResourceCount(rawState)
+	if err != nil {
+		return err
+	}
+	if resourceCount > 0 {
+		return nil
+	}
+
+	return b.TerraformClient.WorkspaceDelete(workspace)
 }
 
 func doesEnvNameClashWithLegacyEnv(envName string, storageDriver storage.Storage) (bool, error) {
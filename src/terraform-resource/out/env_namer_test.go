@@ -0,0 +1,126 @@
+package out_test
+
+import (
+	"errors"
+
+	"github.com/ljfranklin/terraform-resource/models"
+	"github.com/ljfranklin/terraform-resource/namer/namerfakes"
+	"github.com/ljfranklin/terraform-resource/out"
+	"github.com/ljfranklin/terraform-resource/terraform"
+	"github.com/ljfranklin/terraform-resource/terraform/terraformfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BackendEnvNamer", func() {
+
+	var (
+		fakeClient *terraformfakes.FakeClient
+		fakeNamer  *namerfakes.FakeNamer
+		envNamer   out.BackendEnvNamer
+	)
+
+	BeforeEach(func() {
+		fakeClient = &terraformfakes.FakeClient{}
+		fakeClient.WorkspaceListReturns([]string{"default", "existing-env"}, nil)
+		fakeNamer = &namerfakes.FakeNamer{}
+
+		envNamer = out.BackendEnvNamer{
+			Req: models.OutRequest{
+				Source: models.Source{
+					Terraform: models.Terraform{WorkspacePrefix: "team-a-"},
+				},
+				Params: models.OutParams{GenerateRandomName: true},
+			},
+			TerraformClient: fakeClient,
+			Namer:           fakeNamer,
+		}
+	})
+
+	It("reserves the generated name by creating its workspace", func() {
+		fakeNamer.RandomNameReturns("new-env")
+
+		envName, err := envNamer.EnvName()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(envName).To(Equal("new-env"))
+
+		Expect(fakeClient.WorkspaceNewCallCount()).To(Equal(1))
+		Expect(fakeClient.WorkspaceNewArgsForCall(0)).To(Equal("team-a-new-env"))
+	})
+
+	It("picks another name if the workspace was created concurrently", func() {
+		fakeNamer.RandomNameReturnsOnCall(0, "taken-env")
+		fakeNamer.RandomNameReturnsOnCall(1, "new-env")
+		fakeClient.WorkspaceNewReturnsOnCall(0, terraform.ErrWorkspaceExists)
+
+		envName, err := envNamer.EnvName()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(envName).To(Equal("new-env"))
+
+		Expect(fakeClient.WorkspaceNewCallCount()).To(Equal(2))
+		Expect(fakeClient.WorkspaceNewArgsForCall(1)).To(Equal("team-a-new-env"))
+	})
+
+	It("gives up after NameClashRetries attempts", func() {
+		fakeNamer.RandomNameReturns("taken-env")
+		fakeClient.WorkspaceNewReturns(terraform.ErrWorkspaceExists)
+
+		_, err := envNamer.EnvName()
+		Expect(err).To(MatchError(ContainSubstring("Failed to generate a non-clashing random name")))
+		Expect(fakeClient.WorkspaceNewCallCount()).To(Equal(out.NameClashRetries))
+	})
+
+	It("does not pick a name whose plans are still stored", func() {
+		fakeClient.WorkspaceListReturns([]string{"default", "team-a-released-env-plan"}, nil)
+		fakeNamer.RandomNameReturnsOnCall(0, "released-env")
+		fakeNamer.RandomNameReturnsOnCall(1, "new-env")
+
+		envName, err := envNamer.EnvName()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(envName).To(Equal("new-env"))
+		Expect(fakeClient.WorkspaceNewCallCount()).To(Equal(1))
+	})
+
+	It("returns other errors from creating the workspace", func() {
+		fakeNamer.RandomNameReturns("new-env")
+		fakeClient.WorkspaceNewReturns(errors.New("some-error"))
+
+		_, err := envNamer.EnvName()
+		Expect(err).To(MatchError("Failed to reserve workspace 'team-a-new-env': some-error"))
+	})
+
+	Describe("#ReleaseEnvName", func() {
+		BeforeEach(func() {
+			fakeClient.WorkspaceListReturns([]string{"default", "team-a-new-env"}, nil)
+		})
+
+		It("deletes the reserved workspace if it holds no resources", func() {
+			fakeClient.StatePullReturns([]byte{}, nil)
+
+			Expect(envNamer.ReleaseEnvName("new-env")).To(Succeed())
+			Expect(fakeClient.StatePullArgsForCall(0)).To(Equal("team-a-new-env"))
+			Expect(fakeClient.WorkspaceDeleteArgsForCall(0)).To(Equal("team-a-new-env"))
+		})
+
+		It("keeps the workspace if a failed apply left resources behind", func() {
+			fakeClient.StatePullReturns([]byte(`{
+				"version": 4,
+				"serial": 1,
+				"lineage": "aaaaa",
+				"resources": [{"mode": "managed", "type": "aws_instance", "name": "web", "instances": [{}]}]
+			}`), nil)
+
+			Expect(envNamer.ReleaseEnvName("new-env")).To(Succeed())
+			Expect(fakeClient.WorkspaceDeleteCallCount()).To(Equal(0))
+		})
+
+		It("does nothing if the workspace was already deleted", func() {
+			fakeClient.WorkspaceListReturns([]string{"default"}, nil)
+
+			Expect(envNamer.ReleaseEnvName("new-env")).To(Succeed())
+			Expect(fakeClient.StatePullCallCount()).To(Equal(0))
+			Expect(fakeClient.WorkspaceDeleteCallCount()).To(Equal(0))
+		})
+	})
+})
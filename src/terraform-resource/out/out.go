@@ -7,15 +7,29 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ljfranklin/terraform-resource/jsonplan"
 	"github.com/ljfranklin/terraform-resource/logger"
 	"github.com/ljfranklin/terraform-resource/models"
 	"github.com/ljfranklin/terraform-resource/namer"
 	"github.com/ljfranklin/terraform-resource/ssh"
 	"github.com/ljfranklin/terraform-resource/storage"
 	"github.com/ljfranklin/terraform-resource/terraform"
+	"github.com/ljfranklin/terraform-resource/workspaces"
 )
 
+var planNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// checkEnvWorkers bounds how many states are fetched at once for `check_env_pattern`
+const checkEnvWorkers = 4
+
+// defaultMaxReap limits how many envs `action: reap` destroys if `max_reap` is not set
+const defaultMaxReap = 1
+
 type Runner struct {
 	SourceDir string
 	Namer     namer.Namer
@@ -32,7 +46,14 @@ func (r Runner) Run(req models.OutRequest) (models.OutResponse, error) {
 	}
 	defer os.RemoveAll(tmpDir)
 
+	if req.Params.Terraform.WorkspacePrefix != "" {
+		return models.OutResponse{}, errors.New("`workspace_prefix` can only be set in `source`, as `check` and `get` must use the same prefix as `put`")
+	}
+
 	req.Source.Terraform = req.Source.Terraform.Merge(req.Params.Terraform)
+	if err = req.Source.Terraform.Validate(); err != nil {
+		return models.OutResponse{}, fmt.Errorf("Failed to validate terraform Model: %s", err)
+	}
 	terraformModel, err := r.buildTerraformModel(req, tmpDir)
 	if err != nil {
 		return models.OutResponse{}, err
@@ -58,11 +79,81 @@ func (r Runner) Run(req models.OutRequest) (models.OutResponse, error) {
 		}
 	}
 
+	if req.Params.Action == models.DestroyAction && len(terraformModel.Replace) > 0 {
+		return models.OutResponse{}, errors.New("`replace` cannot be used with `action: destroy`, use `targets` to destroy specific resources")
+	}
+
+	if (terraformModel.MaxDestroyCount != nil && *terraformModel.MaxDestroyCount < 0) ||
+		(terraformModel.MaxReplaceCount != nil && *terraformModel.MaxReplaceCount < 0) {
+		return models.OutResponse{}, errors.New("`max_destroy_count` and `max_replace_count` must not be negative")
+	}
+
+	if terraformModel.PlanName != "" && !planNameRegexp.MatchString(terraformModel.PlanName) {
+		return models.OutResponse{}, fmt.Errorf("Invalid `plan_name` '%s', must only contain letters, numbers, '-' and '_'", terraformModel.PlanName)
+	}
+
+	if terraformModel.PlanMaxAge != "" {
+		if _, err := time.ParseDuration(terraformModel.PlanMaxAge); err != nil {
+			return models.OutResponse{}, fmt.Errorf("Failed to parse `plan_max_age`, expected a duration like `24h`: %s", err)
+		}
+	}
+
+	if req.Params.TTL != "" {
+		if ttl, err := time.ParseDuration(req.Params.TTL); err != nil || ttl <= 0 {
+			return models.OutResponse{}, fmt.Errorf("Failed to parse `ttl`, expected a positive duration like `72h`: '%s'", req.Params.TTL)
+		}
+	}
+
+	if terraformModel.CloneFromEnv != "" && (terraformModel.PlanOnly || terraformModel.PlanRun || req.Params.Action != "") {
+		return models.OutResponse{}, errors.New("`clone_from_env` cannot be used with `plan_only`, `plan_run` or `action`")
+	}
+
+	if terraformModel.CloneFromEnv != "" && terraformModel.DeleteOnFailure {
+		return models.OutResponse{}, errors.New("`clone_from_env` cannot be used with `delete_on_failure`, a failed apply would destroy the resources cloned from the other env")
+	}
+
+	if req.Params.MaxReap < 0 {
+		return models.OutResponse{}, errors.New("`max_reap` must not be negative")
+	}
+
+	if req.Params.PlanChecksumFile != "" {
+		if !terraformModel.PlanRun {
+			return models.OutResponse{}, errors.New("`plan_checksum_file` can only be used with `plan_run: true`")
+		}
+		contents, err := ioutil.ReadFile(req.Params.PlanChecksumFile)
+		if err != nil {
+			return models.OutResponse{}, fmt.Errorf("Failed to read `plan_checksum_file` at '%s': %s", req.Params.PlanChecksumFile, err)
+		}
+		terraformModel.PlanChecksum = strings.TrimSpace(string(contents))
+	}
+
+	if req.Params.Terraform.AllowUnverifiedPlan && !terraformModel.PlanRun {
+		return models.OutResponse{}, errors.New("`allow_unverified_plan` can only be used with `plan_run: true`")
+	}
+
+	if len(req.Params.EnvNameWords) > 0 && req.Params.EnvNameTemplate == "" {
+		return models.OutResponse{}, errors.New("`env_name_words` can only be used with `env_name_template`")
+	}
+
+	if req.Params.EnvNameTemplate != "" {
+		r.Namer, err = namer.NewFromTemplate(req.Params.EnvNameTemplate, req.Params.EnvNameWords, namer.BuildMetadataFromEnv())
+		if err != nil {
+			return models.OutResponse{}, err
+		}
+	}
+
 	if req.Source.BackendType == "local" {
 		return models.OutResponse{},
 			errors.New("backend type 'local' is not supported, Concourse requires that state is persisted outside the container; use one of the other backend types listed here: https://www.terraform.io/docs/backends/types/index.html")
 	}
 
+	if req.Params.Action == models.ReapAction {
+		if req.Source.BackendType == "" {
+			return models.OutResponse{}, errors.New("`action: reap` is only supported with `backend_type`")
+		}
+		return r.runReap(req, terraformModel)
+	}
+
 	if req.Source.BackendType != "" && req.Source.MigratedFromStorage != (storage.Model{}) {
 		return r.runWithMigratedFromStorage(req, terraformModel)
 	} else if req.Source.BackendType == "" {
@@ -108,22 +199,38 @@ func (r Runner) runWithBackend(req models.OutRequest, terraformModel models.Terr
 		result, actionErr = action.Plan()
 	} else if req.Params.Action == models.DestroyAction {
 		result, actionErr = action.Destroy()
+	} else if req.Params.Action == models.RefreshAction {
+		result, actionErr = action.Refresh()
 	} else {
 		result, actionErr = action.Apply()
 	}
+	if req.Params.GeneratesEnvName() && req.Params.Action == "" && (req.Params.PlanOnly || actionErr != nil) {
+		r.releaseEnvName(req, client, envName)
+	}
 	if actionErr != nil {
 		return models.OutResponse{}, actionErr
 	}
 
+	var envMetadata terraform.EnvMetadata
+	if req.Params.RecordsEnvMetadata() && !req.Params.PlanOnly && req.Params.Action == "" {
+		if envMetadata, err = r.recordEnvMetadata(req.Params, client, terraformModel, envName); err != nil {
+			return models.OutResponse{}, err
+		}
+	}
+
 	version := result.Version
 	if req.Params.PlanOnly {
 		version.PlanOnly = "true" // Concourse demands version fields are strings
 	}
+	if req.Source.CheckEnvPattern != "" {
+		version.CheckedEnvs = r.checkedEnvs(req, client)
+	}
 
-	metadata, err := r.buildMetadata(result.SanitizedOutput(), client)
+	metadata, err := r.buildMetadata(result, terraformModel, client)
 	if err != nil {
 		return models.OutResponse{}, actionErr
 	}
+	metadata = append(metadata, envMetadataFields(envMetadata)...)
 
 	resp := models.OutResponse{
 		Version:  version,
@@ -145,6 +252,46 @@ func (r Runner) runWithLegacyStorage(req models.OutRequest, terraformModel model
 	}
 	defer os.RemoveAll(tmpDir)
 
+	if len(terraformModel.Targets) > 0 || len(terraformModel.Replace) > 0 {
+		return models.OutResponse{}, errors.New("`targets` and `replace` are only supported with `backend_type`")
+	}
+
+	if req.Params.Action == models.RefreshAction {
+		return models.OutResponse{}, errors.New("`action: refresh` is only supported with `backend_type`")
+	}
+
+	if terraformModel.PolicyFile != "" {
+		return models.OutResponse{}, errors.New("`policy_file` is only supported with `backend_type`")
+	}
+
+	if terraformModel.MaxDestroyCount != nil || terraformModel.MaxReplaceCount != nil {
+		return models.OutResponse{}, errors.New("`max_destroy_count` and `max_replace_count` are only supported with `backend_type`")
+	}
+
+	if terraformModel.PlanChecksum != "" {
+		return models.OutResponse{}, errors.New("`plan_checksum_file` is only supported with `backend_type`")
+	}
+
+	if terraformModel.PlanMaxAge != "" {
+		return models.OutResponse{}, errors.New("`plan_max_age` is only supported with `backend_type`")
+	}
+
+	if terraformModel.PlanName != "" {
+		return models.OutResponse{}, errors.New("`plan_name` is only supported with `backend_type`")
+	}
+
+	if req.Params.RecordsEnvMetadata() {
+		return models.OutResponse{}, errors.New("`ttl` and `labels` are only supported with `backend_type`")
+	}
+
+	if terraformModel.CloneFromEnv != "" {
+		return models.OutResponse{}, errors.New("`clone_from_env` is only supported with `backend_type`")
+	}
+
+	if terraformModel.PlanEncryptionKey != "" {
+		return models.OutResponse{}, errors.New("`plan_encryption_key` is only supported with `backend_type`")
+	}
+
 	storageModel := req.Source.Storage
 	if err = storageModel.Validate(); err != nil {
 		return models.OutResponse{}, fmt.Errorf("Failed to validate storage Model: %s", err)
@@ -203,7 +350,7 @@ func (r Runner) runWithLegacyStorage(req models.OutRequest, terraformModel model
 		version.PlanOnly = "true" // Concourse demands version fields are strings
 	}
 
-	metadata, err := r.buildMetadata(result.SanitizedOutput(), client)
+	metadata, err := r.buildMetadata(terraform.Result{Output: result.Output}, terraformModel, client)
 	if err != nil {
 		return models.OutResponse{}, actionErr
 	}
@@ -223,6 +370,10 @@ func (r Runner) runWithMigratedFromStorage(req models.OutRequest, terraformModel
 	}
 	defer os.RemoveAll(tmpDir)
 
+	if terraformModel.CloneFromEnv != "" {
+		return models.OutResponse{}, errors.New("`clone_from_env` cannot be used with `migrated_from_storage`")
+	}
+
 	storageModel := req.Source.MigratedFromStorage
 	if err = storageModel.Validate(); err != nil {
 		return models.OutResponse{}, fmt.Errorf("Failed to validate storage Model: %s", err)
@@ -268,22 +419,38 @@ func (r Runner) runWithMigratedFromStorage(req models.OutRequest, terraformModel
 		result, actionErr = action.Plan()
 	} else if req.Params.Action == models.DestroyAction {
 		result, actionErr = action.Destroy()
+	} else if req.Params.Action == models.RefreshAction {
+		result, actionErr = action.Refresh()
 	} else {
 		result, actionErr = action.Apply()
 	}
+	if req.Params.GeneratesEnvName() && req.Params.Action == "" && (req.Params.PlanOnly || actionErr != nil) {
+		r.releaseEnvName(req, client, envName)
+	}
 	if actionErr != nil {
 		return models.OutResponse{}, actionErr
 	}
 
+	var envMetadata terraform.EnvMetadata
+	if req.Params.RecordsEnvMetadata() && !req.Params.PlanOnly && req.Params.Action == "" {
+		if envMetadata, err = r.recordEnvMetadata(req.Params, client, terraformModel, envName); err != nil {
+			return models.OutResponse{}, err
+		}
+	}
+
 	version := result.Version
 	if req.Params.PlanOnly {
 		version.PlanOnly = "true" // Concourse demands version fields are strings
 	}
+	if req.Source.CheckEnvPattern != "" {
+		version.CheckedEnvs = r.checkedEnvs(req, client)
+	}
 
-	metadata, err := r.buildMetadata(result.SanitizedOutput(), client)
+	metadata, err := r.buildMetadata(result, terraformModel, client)
 	if err != nil {
 		return models.OutResponse{}, actionErr
 	}
+	metadata = append(metadata, envMetadataFields(envMetadata)...)
 
 	resp := models.OutResponse{
 		Version:  version,
@@ -293,6 +460,89 @@ func (r Runner) runWithMigratedFromStorage(req models.OutRequest, terraformModel
 	return resp, nil
 }
 
+func (r Runner) runReap(req models.OutRequest, terraformModel models.Terraform) (models.OutResponse, error) {
+	client := terraform.NewClient(
+		terraformModel,
+		r.LogWriter,
+	)
+
+	maxReap := req.Params.MaxReap
+	if maxReap == 0 {
+		maxReap = defaultMaxReap
+	}
+	reaper := terraform.Reaper{
+		Client:  client,
+		Model:   terraformModel,
+		MaxReap: maxReap,
+		DryRun:  req.Params.DryRun,
+		Logger: logger.Logger{
+			Sink: r.LogWriter,
+		},
+	}
+
+	now := time.Now().UTC()
+	result, err := reaper.Reap(now)
+	if err != nil {
+		return models.OutResponse{}, err
+	}
+
+	metadata := []models.MetadataField{
+		{Name: "expired", Value: strings.Join(result.Expired, ", ")},
+		{Name: "reaped", Value: strings.Join(result.Reaped, ", ")},
+	}
+	if req.Params.DryRun {
+		metadata = append(metadata, models.MetadataField{Name: "dry_run", Value: "true"})
+	}
+
+	resp := models.OutResponse{
+		// reaping touches many envs, so the version only records when it ran
+		Version: models.Version{
+			EnvName:      models.ReapAction,
+			LastModified: now.Format(models.TimeFormat),
+			Reap:         "true",
+		},
+		Metadata: metadata,
+	}
+
+	return resp, nil
+}
+
+// recordEnvMetadata stores when the env expires so that `action: reap` can
+// destroy it, along with any labels shown by `list_envs`
+func (r Runner) recordEnvMetadata(params models.OutParams, client terraform.Client, terraformModel models.Terraform, envName string) (terraform.EnvMetadata, error) {
+	now := time.Now().UTC()
+	build := namer.BuildMetadataFromEnv()
+	metadata := terraform.EnvMetadata{
+		CreatedAt:    now,
+		TeamName:     build.TeamName,
+		PipelineName: build.PipelineName,
+		JobName:      build.JobName,
+		BuildName:    build.Name,
+		Labels:       params.Labels,
+	}
+
+	if params.TTL != "" {
+		duration, err := time.ParseDuration(params.TTL)
+		if err != nil {
+			return terraform.EnvMetadata{}, err
+		}
+		metadata.ExpiresAt = now.Add(duration)
+	}
+
+	return terraform.RecordEnvMetadata(client, terraformModel, envName, metadata)
+}
+
+func envMetadataFields(envMetadata terraform.EnvMetadata) []models.MetadataField {
+	fields := []models.MetadataField{}
+	if !envMetadata.CreatedAt.IsZero() {
+		fields = append(fields, models.MetadataField{Name: "created_at", Value: envMetadata.CreatedAt.Format(models.TimeFormat)})
+	}
+	if !envMetadata.ExpiresAt.IsZero() {
+		fields = append(fields, models.MetadataField{Name: "expires_at", Value: envMetadata.ExpiresAt.Format(models.TimeFormat)})
+	}
+	return fields
+}
+
 func (r Runner) buildEnvName(req models.OutRequest, terraformModel models.Terraform) (string, error) {
 	tfClientWithoutWorkspace := terraform.NewClient(
 		terraformModel,
@@ -306,6 +556,36 @@ func (r Runner) buildEnvName(req models.OutRequest, terraformModel models.Terraf
 	return namer.EnvName()
 }
 
+// releaseEnvName frees the workspace reserved for a generated name, failures
+// are only logged as the put has either failed already or only planned
+func (r Runner) releaseEnvName(req models.OutRequest, client terraform.Client, envName string) {
+	namer := BackendEnvNamer{
+		Req:             req,
+		TerraformClient: client,
+	}
+	if err := namer.ReleaseEnvName(envName); err != nil {
+		logger := logger.Logger{
+			Sink: r.LogWriter,
+		}
+		logger.Warn(fmt.Sprintf("Failed to release the workspace of generated name '%s': %s", envName, err))
+	}
+}
+
+// checkedEnvs records the envs matching `check_env_pattern` like `check` does,
+// otherwise the next `check` would report every env again after this version
+func (r Runner) checkedEnvs(req models.OutRequest, client terraform.Client) string {
+	pattern := regexp.MustCompile(req.Source.CheckEnvPattern) // checked by Source.Validate
+	latestVersions, err := workspaces.NewWithPrefix(client, req.Source.WorkspacePrefix).LatestVersionsMatching(pattern, checkEnvWorkers)
+	if err != nil {
+		logger := logger.Logger{
+			Sink: r.LogWriter,
+		}
+		logger.Warn(fmt.Sprintf("Failed to record the envs matching `check_env_pattern`, the next check reports all of them again: %s", err))
+		return ""
+	}
+	return workspaces.Snapshot(latestVersions)
+}
+
 func (r Runner) buildEnvNameFromLegacyStorage(req models.OutRequest, storageDriver storage.Storage) (string, error) {
 	namer := LegacyStorageEnvNamer{
 		Req:           req,
@@ -336,6 +616,9 @@ func (r Runner) buildTerraformModel(req models.OutRequest, tmpDir string) (model
 			terraformModel.VarFiles[i] = path.Join(r.SourceDir, terraformModel.VarFiles[i])
 		}
 	}
+	if terraformModel.PolicyFile != "" {
+		terraformModel.PolicyFile = path.Join(r.SourceDir, terraformModel.PolicyFile)
+	}
 	if err := terraformModel.ConvertVarFiles(tmpDir); err != nil {
 		return models.Terraform{}, fmt.Errorf("Failed to parse `terraform.var_files`: %s", err)
 	}
@@ -359,12 +642,30 @@ func (r Runner) buildTerraformModel(req models.OutRequest, tmpDir string) (model
 	return terraformModel, nil
 }
 
-func (r Runner) buildMetadata(outputs map[string]string, client terraform.Client) ([]models.MetadataField, error) {
-	metadata := []models.MetadataField{}
-	for key, value := range outputs {
+func (r Runner) buildMetadata(result terraform.Result, terraformModel models.Terraform, client terraform.Client) ([]models.MetadataField, error) {
+	metadata := result.Metadata(terraformModel)
+
+	// make partial applies visible to reviewers
+	if len(terraformModel.Targets) > 0 {
 		metadata = append(metadata, models.MetadataField{
-			Name:  key,
-			Value: value,
+			Name:  "targets",
+			Value: strings.Join(terraformModel.Targets, ", "),
+		})
+	}
+	if len(terraformModel.Replace) > 0 {
+		metadata = append(metadata, models.MetadataField{
+			Name:  "replace",
+			Value: strings.Join(terraformModel.Replace, ", "),
+		})
+	}
+
+	if result.PlanSummary != nil {
+		metadata = append(metadata, planSummaryMetadata(*result.PlanSummary)...)
+	}
+	if result.PendingPlans != nil {
+		metadata = append(metadata, models.MetadataField{
+			Name:  "pending_plans",
+			Value: strings.Join(result.PendingPlans, ", "),
 		})
 	}
 
@@ -377,3 +678,23 @@ func (r Runner) buildMetadata(outputs map[string]string, client terraform.Client
 		Value: tfVersion,
 	}), nil
 }
+
+// keeps the Concourse UI readable for plans touching many resources
+const maxPlanSummaryAddresses = 20
+
+func planSummaryMetadata(summary jsonplan.Summary) []models.MetadataField {
+	addresses := summary.Addresses
+	if len(addresses) > maxPlanSummaryAddresses {
+		addresses = append(addresses[:maxPlanSummaryAddresses:maxPlanSummaryAddresses],
+			fmt.Sprintf("... and %d more", len(summary.Addresses)-maxPlanSummaryAddresses))
+	}
+
+	return []models.MetadataField{
+		{Name: "has_changes", Value: strconv.FormatBool(summary.HasChanges())},
+		{Name: "to_add", Value: strconv.Itoa(summary.Add)},
+		{Name: "to_change", Value: strconv.Itoa(summary.Change)},
+		{Name: "to_destroy", Value: strconv.Itoa(summary.Destroy)},
+		{Name: "to_replace", Value: strconv.Itoa(summary.Replace)},
+		{Name: "changed_resources", Value: strings.Join(addresses, ", ")},
+	}
+}
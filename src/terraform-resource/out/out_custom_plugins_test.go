@@ -127,9 +127,10 @@ var _ = Describe("Out Lifecycle with Custom Plugins", func() {
 			Params: models.OutParams{
 				EnvName: envName,
 				Terraform: models.Terraform{
-					Source:    "fixtures/aws/",
-					PluginDir: pluginDir,
-					PlanRun:   true,
+					Source:              "fixtures/aws/",
+					PluginDir:           pluginDir,
+					PlanRun:             true,
+					AllowUnverifiedPlan: true,
 				},
 			},
 		}
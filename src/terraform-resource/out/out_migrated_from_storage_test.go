@@ -649,8 +649,9 @@ var _ = Describe("Out - Migrated From Storage", func() {
 				Params: models.OutParams{
 					EnvName: envName,
 					Terraform: models.Terraform{
-						Source:  "fixtures/aws/",
-						PlanRun: true,
+						Source:              "fixtures/aws/",
+						PlanRun:             true,
+						AllowUnverifiedPlan: true,
 						Env: map[string]string{
 							"HOME": workingDir, // in prod plugin is installed system-wide
 						},
@@ -786,8 +787,9 @@ var _ = Describe("Out - Migrated From Storage", func() {
 				Params: models.OutParams{
 					EnvName: envName,
 					Terraform: models.Terraform{
-						Source:  "fixtures/aws/",
-						PlanRun: true,
+						Source:              "fixtures/aws/",
+						PlanRun:             true,
+						AllowUnverifiedPlan: true,
 						Env: map[string]string{
 							"HOME": workingDir, // in prod plugin is installed system-wide
 						},
@@ -987,8 +989,9 @@ var _ = Describe("Out - Migrated From Storage", func() {
 				Params: models.OutParams{
 					EnvName: envName,
 					Terraform: models.Terraform{
-						Source:  "fixtures/aws/",
-						PlanRun: true,
+						Source:              "fixtures/aws/",
+						PlanRun:             true,
+						AllowUnverifiedPlan: true,
 						Env: map[string]string{
 							"HOME": workingDir, // in prod plugin is installed system-wide
 						},
@@ -7,10 +7,12 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/ljfranklin/terraform-resource/models"
 	"github.com/ljfranklin/terraform-resource/out"
+	"github.com/ljfranklin/terraform-resource/storage"
 	"github.com/ljfranklin/terraform-resource/test/helpers"
 
 	. "github.com/onsi/ginkgo"
@@ -114,8 +116,9 @@ var _ = Describe("Out Plan", func() {
 			Params: models.OutParams{
 				EnvName: envName,
 				Terraform: models.Terraform{
-					Source:  "fixtures/aws/",
-					PlanRun: true,
+					Source:              "fixtures/aws/",
+					PlanRun:             true,
+					AllowUnverifiedPlan: true,
 					Env: map[string]string{
 						"HOME": workingDir, // in prod plugin is installed system-wide
 					},
@@ -146,6 +149,18 @@ var _ = Describe("Out Plan", func() {
 		Expect(planOutput.Version.PlanOnly).To(Equal("true"), "Expected PlanOnly to be true, but was false")
 		Expect(planOutput.Version.Serial).To(BeEmpty())
 		Expect(planOutput.Version.PlanChecksum).To(MatchRegexp("[0-9|a-f]+"))
+		Expect(planOutput.Version.HasChanges).To(Equal("true"))
+
+		planFields := map[string]interface{}{}
+		for _, field := range planOutput.Metadata {
+			planFields[field.Name] = field.Value
+		}
+		Expect(planFields["has_changes"]).To(Equal("true"))
+		Expect(planFields["to_add"]).To(Equal("1"))
+		Expect(planFields["to_change"]).To(Equal("0"))
+		Expect(planFields["to_destroy"]).To(Equal("0"))
+		Expect(planFields["to_replace"]).To(Equal("0"))
+		Expect(planFields["changed_resources"]).To(Equal("+ aws_s3_bucket_object.s3_object"))
 
 		Expect(path.Join(os.TempDir(), "tf-plan.log")).To(BeAnExistingFile())
 
@@ -192,6 +207,345 @@ var _ = Describe("Out Plan", func() {
 		Expect(err).ToNot(HaveOccurred())
 	})
 
+	It("refuses to apply a plan which does not match `plan_checksum_file`", func() {
+		planOutRequest := models.OutRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType:   backendType,
+					BackendConfig: backendConfig,
+				},
+			},
+			Params: models.OutParams{
+				EnvName: envName,
+				Terraform: models.Terraform{
+					Source:   "fixtures/aws/",
+					PlanOnly: true,
+					Env: map[string]string{
+						"HOME": workingDir, // in prod plugin is installed system-wide
+					},
+					Vars: map[string]interface{}{
+						"access_key":     accessKey,
+						"secret_key":     secretKey,
+						"bucket":         bucket,
+						"object_key":     s3ObjectPath,
+						"object_content": "terraform-is-neat",
+						"region":         region,
+					},
+				},
+			},
+		}
+
+		runner := out.Runner{
+			SourceDir: workingDir,
+			LogWriter: GinkgoWriter,
+		}
+
+		By("planning twice so the reviewed plan is replaced")
+
+		reviewedPlanOutput, err := runner.Run(planOutRequest)
+		Expect(err).ToNot(HaveOccurred())
+
+		planOutRequest.Params.Terraform.Vars["object_content"] = "terraform-is-sneaky"
+		latestPlanOutput, err := runner.Run(planOutRequest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(latestPlanOutput.Version.PlanChecksum).ToNot(Equal(reviewedPlanOutput.Version.PlanChecksum))
+
+		checksumPath := path.Join(workingDir, "plan_checksum")
+		err = ioutil.WriteFile(checksumPath, []byte(reviewedPlanOutput.Version.PlanChecksum), 0644)
+		Expect(err).ToNot(HaveOccurred())
+
+		applyRequest := models.OutRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType:   backendType,
+					BackendConfig: backendConfig,
+				},
+			},
+			Params: models.OutParams{
+				EnvName:          envName,
+				PlanChecksumFile: checksumPath,
+				Terraform: models.Terraform{
+					Source:  "fixtures/aws/",
+					PlanRun: true,
+					Env: map[string]string{
+						"HOME": workingDir, // in prod plugin is installed system-wide
+					},
+				},
+			},
+		}
+
+		By("applying with the checksum of the reviewed plan")
+
+		_, err = runner.Run(applyRequest)
+		Expect(err).To(MatchError(ContainSubstring("Plan checksum mismatch")))
+
+		awsVerifier.ExpectS3FileToNotExist(
+			bucket,
+			s3ObjectPath,
+		)
+	})
+
+	It("refuses to apply a stale plan", func() {
+		planOutRequest := models.OutRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType:   backendType,
+					BackendConfig: backendConfig,
+				},
+			},
+			Params: models.OutParams{
+				EnvName: envName,
+				Terraform: models.Terraform{
+					Source:   "fixtures/aws/",
+					PlanOnly: true,
+					Env: map[string]string{
+						"HOME": workingDir, // in prod plugin is installed system-wide
+					},
+					Vars: map[string]interface{}{
+						"access_key":     accessKey,
+						"secret_key":     secretKey,
+						"bucket":         bucket,
+						"object_key":     s3ObjectPath,
+						"object_content": "terraform-is-neat",
+						"region":         region,
+					},
+				},
+			},
+		}
+
+		applyRequest := models.OutRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType:   backendType,
+					BackendConfig: backendConfig,
+				},
+			},
+			Params: models.OutParams{
+				EnvName: envName,
+				Terraform: models.Terraform{
+					Source:              "fixtures/aws/",
+					PlanRun:             true,
+					AllowUnverifiedPlan: true,
+					PlanMaxAge:          "1ns",
+					Env: map[string]string{
+						"HOME": workingDir, // in prod plugin is installed system-wide
+					},
+				},
+			},
+		}
+
+		runner := out.Runner{
+			SourceDir: workingDir,
+			LogWriter: GinkgoWriter,
+		}
+
+		By("applying a plan older than `plan_max_age`")
+
+		_, err := runner.Run(planOutRequest)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = runner.Run(applyRequest)
+		Expect(err).To(MatchError(ContainSubstring("exceeds `plan_max_age` of 1ns")))
+
+		By("applying a plan after the state has changed")
+
+		// the plan is always computed against an empty state for a new env,
+		// so create the object first and plan against an existing state
+		directApplyRequest := planOutRequest
+		directApplyRequest.Params.Terraform.PlanOnly = false
+		_, err = runner.Run(directApplyRequest)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = runner.Run(planOutRequest)
+		Expect(err).ToNot(HaveOccurred())
+
+		// a refresh after an out-of-band change records a new state serial
+		awsVerifier.UploadObjectToS3(bucket, s3ObjectPath, strings.NewReader("changed-by-hand"))
+		refreshRequest := directApplyRequest
+		refreshRequest.Params.Action = models.RefreshAction
+		_, err = runner.Run(refreshRequest)
+		Expect(err).ToNot(HaveOccurred())
+
+		applyRequest.Params.Terraform.PlanMaxAge = ""
+		_, err = runner.Run(applyRequest)
+		Expect(err).To(MatchError(ContainSubstring("Plan is stale: it was computed against serial")))
+	})
+
+	It("keeps named plans side by side and cleans them up after apply", func() {
+		planOutRequest := models.OutRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType:   backendType,
+					BackendConfig: backendConfig,
+				},
+			},
+			Params: models.OutParams{
+				EnvName: envName,
+				Terraform: models.Terraform{
+					Source:   "fixtures/aws/",
+					PlanOnly: true,
+					PlanName: "feature-a",
+					Env: map[string]string{
+						"HOME": workingDir, // in prod plugin is installed system-wide
+					},
+					Vars: map[string]interface{}{
+						"access_key":     accessKey,
+						"secret_key":     secretKey,
+						"bucket":         bucket,
+						"object_key":     s3ObjectPath,
+						"object_content": "feature-a",
+						"region":         region,
+					},
+				},
+			},
+		}
+
+		runner := out.Runner{
+			SourceDir: workingDir,
+			LogWriter: GinkgoWriter,
+		}
+
+		By("saving two named plans")
+
+		featureAOutput, err := runner.Run(planOutRequest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(featureAOutput.Version.PlanName).To(Equal("feature-a"))
+
+		planOutRequest.Params.Terraform.PlanName = "feature-b"
+		planOutRequest.Params.Terraform.Vars["object_content"] = "feature-b"
+		featureBOutput, err := runner.Run(planOutRequest)
+		Expect(err).ToNot(HaveOccurred())
+
+		fields := map[string]interface{}{}
+		for _, field := range featureBOutput.Metadata {
+			fields[field.Name] = field.Value
+		}
+		Expect(fields["pending_plans"]).To(Equal("feature-a, feature-b"))
+
+		featureAPlanPath := path.Join(workspacePath, fmt.Sprintf("%s-plan.feature-a", envName), "terraform.tfstate")
+		featureBPlanPath := path.Join(workspacePath, fmt.Sprintf("%s-plan.feature-b", envName), "terraform.tfstate")
+		awsVerifier.ExpectS3FileToExist(bucket, featureAPlanPath)
+		awsVerifier.ExpectS3FileToExist(bucket, featureBPlanPath)
+
+		By("applying the first plan")
+
+		applyRequest := models.OutRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType:   backendType,
+					BackendConfig: backendConfig,
+				},
+			},
+			Params: models.OutParams{
+				EnvName: envName,
+				Terraform: models.Terraform{
+					Source:              "fixtures/aws/",
+					PlanRun:             true,
+					AllowUnverifiedPlan: true,
+					PlanName:            "feature-a",
+					Env: map[string]string{
+						"HOME": workingDir, // in prod plugin is installed system-wide
+					},
+				},
+			},
+		}
+		createOutput, err := runner.Run(applyRequest)
+		Expect(err).ToNot(HaveOccurred())
+
+		fields = map[string]interface{}{}
+		for _, field := range createOutput.Metadata {
+			fields[field.Name] = field.Value
+		}
+		expectedMD5 := fmt.Sprintf("%x", md5.Sum([]byte("feature-a")))
+		Expect(fields["content_md5"]).To(Equal(expectedMD5))
+
+		By("ensuring that all plans for the env were deleted")
+
+		awsVerifier.ExpectS3FileToNotExist(bucket, featureAPlanPath)
+		awsVerifier.ExpectS3FileToNotExist(bucket, featureBPlanPath)
+	})
+
+	It("stores plans in `plan_storage` instead of a plan workspace", func() {
+		planStorage := storage.Model{
+			Driver:    storage.FSDriver,
+			Directory: path.Join(workingDir, "plans"),
+		}
+
+		planOutRequest := models.OutRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType:   backendType,
+					BackendConfig: backendConfig,
+					PlanStorage:   planStorage,
+				},
+			},
+			Params: models.OutParams{
+				EnvName: envName,
+				Terraform: models.Terraform{
+					Source:   "fixtures/aws/",
+					PlanOnly: true,
+					Vars: map[string]interface{}{
+						"access_key":     accessKey,
+						"secret_key":     secretKey,
+						"bucket":         bucket,
+						"object_key":     s3ObjectPath,
+						"object_content": "terraform-is-neat",
+						"region":         region,
+					},
+				},
+			},
+		}
+
+		runner := out.Runner{
+			SourceDir: workingDir,
+			LogWriter: GinkgoWriter,
+		}
+
+		By("saving the plan files keyed by env and checksum")
+
+		planOutput, err := runner.Run(planOutRequest)
+		Expect(err).ToNot(HaveOccurred())
+
+		storedPlanDir := path.Join(planStorage.Directory, fmt.Sprintf("%s-plan", envName), planOutput.Version.PlanChecksum)
+		Expect(path.Join(storedPlanDir, "plan.tfplan")).To(BeARegularFile())
+		Expect(path.Join(storedPlanDir, "plan.json")).To(BeARegularFile())
+		Expect(path.Join(storedPlanDir, "plan.txt")).To(BeARegularFile())
+		awsVerifier.ExpectS3FileToNotExist(bucket, planFilePath)
+
+		By("applying the stored plan")
+
+		applyRequest := models.OutRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType:   backendType,
+					BackendConfig: backendConfig,
+					PlanStorage:   planStorage,
+				},
+			},
+			Params: models.OutParams{
+				EnvName: envName,
+				Terraform: models.Terraform{
+					Source:              "fixtures/aws/",
+					PlanRun:             true,
+					AllowUnverifiedPlan: true,
+				},
+			},
+		}
+		createOutput, err := runner.Run(applyRequest)
+		Expect(err).ToNot(HaveOccurred())
+
+		fields := map[string]interface{}{}
+		for _, field := range createOutput.Metadata {
+			fields[field.Name] = field.Value
+		}
+		expectedMD5 := fmt.Sprintf("%x", md5.Sum([]byte("terraform-is-neat")))
+		Expect(fields["content_md5"]).To(Equal(expectedMD5))
+
+		By("ensuring that the stored plan was deleted")
+
+		Expect(path.Join(storedPlanDir, "plan.tfplan")).ToNot(BeAnExistingFile())
+	})
+
 	It("takes the existing statefile into account when generating a plan", func() {
 		initialApplyRequest := models.OutRequest{
 			Source: models.Source{
@@ -256,8 +610,9 @@ var _ = Describe("Out Plan", func() {
 			Params: models.OutParams{
 				EnvName: envName,
 				Terraform: models.Terraform{
-					Source:  "fixtures/aws/",
-					PlanRun: true,
+					Source:              "fixtures/aws/",
+					PlanRun:             true,
+					AllowUnverifiedPlan: true,
 					Env: map[string]string{
 						"HOME": workingDir, // in prod plugin is installed system-wide
 					},
@@ -596,6 +596,45 @@ map_of_maps = {
 		assertOutBehavior(req, expectedMetadata)
 	})
 
+	It("refreshes outputs without changing IaaS resources when `action: refresh` is given", func() {
+		req := models.OutRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType:   backendType,
+					BackendConfig: backendConfig,
+				},
+			},
+			Params: models.OutParams{
+				EnvName: envName,
+				Terraform: models.Terraform{
+					Source: "fixtures/aws/",
+					Vars: map[string]interface{}{
+						"access_key":     accessKey,
+						"secret_key":     secretKey,
+						"bucket":         bucket,
+						"object_key":     s3ObjectPath,
+						"object_content": "terraform-is-neat",
+						"region":         region,
+					},
+				},
+			},
+		}
+		assertOutBehavior(req, map[string]string{
+			"content_md5": calculateMD5("terraform-is-neat"),
+		})
+
+		// modify the object outside of Terraform
+		awsVerifier.UploadObjectToS3(bucket, s3ObjectPath, strings.NewReader("changed-by-hand"))
+
+		logWriter.Reset()
+		req.Params.Action = models.RefreshAction
+		assertOutBehavior(req, map[string]string{
+			"content_md5": calculateMD5("changed-by-hand"),
+		})
+		Expect(logWriter.String()).To(ContainSubstring("Terraform Refresh"))
+		Expect(logWriter.String()).To(ContainSubstring("0 added, 0 changed, 0 destroyed"))
+	})
+
 	It("redacts sensitive outputs in metadata and logs", func() {
 		req := models.OutRequest{
 			Source: models.Source{
@@ -662,6 +701,86 @@ map_of_maps = {
 		Expect(logWriter.String()).To(ContainSubstring("bucket"))
 	})
 
+	It("does not apply a plan that violates `policy_file`", func() {
+		policyPath := path.Join(workingDir, "policy.yml")
+		err := ioutil.WriteFile(policyPath, []byte("deny_actions:\n- actions: [create]\n  resource_types: [aws_s3_*]\n"), 0644)
+		Expect(err).ToNot(HaveOccurred())
+
+		req := models.OutRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType:   backendType,
+					BackendConfig: backendConfig,
+				},
+			},
+			Params: models.OutParams{
+				EnvName: envName,
+				Terraform: models.Terraform{
+					Source:     "fixtures/aws/",
+					PolicyFile: "policy.yml",
+					Vars: map[string]interface{}{
+						"access_key":     accessKey,
+						"secret_key":     secretKey,
+						"bucket":         bucket,
+						"object_key":     s3ObjectPath,
+						"object_content": "terraform-is-neat",
+						"region":         region,
+					},
+				},
+			},
+		}
+
+		runner := out.Runner{
+			SourceDir: workingDir,
+			LogWriter: &logWriter,
+			Namer:     &namer,
+		}
+		_, err = runner.Run(req)
+		Expect(err).To(MatchError(ContainSubstring("create is denied for resource type 'aws_s3_bucket_object'")))
+		awsVerifier.ExpectS3FileToNotExist(bucket, s3ObjectPath)
+	})
+
+	It("does not apply a plan that exceeds `max_replace_count`", func() {
+		req := models.OutRequest{
+			Source: models.Source{
+				Terraform: models.Terraform{
+					BackendType:   backendType,
+					BackendConfig: backendConfig,
+				},
+			},
+			Params: models.OutParams{
+				EnvName: envName,
+				Terraform: models.Terraform{
+					Source: "fixtures/aws/",
+					Vars: map[string]interface{}{
+						"access_key":     accessKey,
+						"secret_key":     secretKey,
+						"bucket":         bucket,
+						"object_key":     s3ObjectPath,
+						"object_content": "terraform-is-neat",
+						"region":         region,
+					},
+				},
+			},
+		}
+		assertOutBehavior(req, map[string]string{
+			"content_md5": calculateMD5("terraform-is-neat"),
+		})
+
+		maxReplaceCount := 0
+		req.Params.Terraform.Replace = []string{"aws_s3_bucket_object.s3_object"}
+		req.Params.Terraform.MaxReplaceCount = &maxReplaceCount
+
+		runner := out.Runner{
+			SourceDir: workingDir,
+			LogWriter: &logWriter,
+			Namer:     &namer,
+		}
+		_, err := runner.Run(req)
+		Expect(err).To(MatchError(ContainSubstring("Plan replaces 1 resource(s), exceeding `max_replace_count` of 0")))
+		Expect(err).To(MatchError(ContainSubstring("aws_s3_bucket_object.s3_object")))
+	})
+
 	It("replaces spaces in env_name with hyphens", func() {
 		spaceName := strings.Replace(envName, "-", " ", -1)
 		req := models.OutRequest{
@@ -1106,6 +1225,70 @@ map_of_maps = {
 			awsVerifier.ExpectS3FileToNotExist(bucket, originalStateFilePath)
 			awsVerifier.ExpectS3FileToNotExist(bucket, stateFilePath)
 		})
+
+		It("only applies the given `targets` and records them in metadata", func() {
+			req.Params.Terraform.Targets = []string{"aws_s3_bucket_object.s3_object"}
+
+			expectedMetadata := map[string]string{
+				"env_name":    envName,
+				"content_md5": calculateMD5("terraform-is-neat"),
+				"targets":     "aws_s3_bucket_object.s3_object",
+			}
+			assertOutBehavior(req, expectedMetadata)
+			awsVerifier.ExpectS3FileToExist(bucket, s3ObjectPath)
+
+			By("replacing the targeted resource when `replace` is given")
+			req.Params.Terraform.Replace = []string{"aws_s3_bucket_object.s3_object"}
+			expectedMetadata["replace"] = "aws_s3_bucket_object.s3_object"
+			assertOutBehavior(req, expectedMetadata)
+			Expect(logWriter.String()).To(ContainSubstring("1 added, 0 changed, 1 destroyed"))
+
+			By("destroying only the targeted resources")
+			req.Params.Terraform.Replace = nil
+			req.Params.Action = models.DestroyAction
+			runner := out.Runner{
+				SourceDir: workingDir,
+				LogWriter: &logWriter,
+			}
+			_, err := runner.Run(req)
+			Expect(err).ToNot(HaveOccurred())
+			awsVerifier.ExpectS3FileToNotExist(bucket, s3ObjectPath)
+		})
+
+		It("returns an error if `replace` is given with `action: destroy`", func() {
+			req.Params.Terraform.Replace = []string{"aws_s3_bucket_object.s3_object"}
+			req.Params.Action = models.DestroyAction
+
+			runner := out.Runner{
+				SourceDir: workingDir,
+				LogWriter: &logWriter,
+			}
+			_, err := runner.Run(req)
+			Expect(err).To(MatchError(ContainSubstring("`replace` cannot be used with `action: destroy`")))
+		})
+
+		It("returns an error if `workspace_prefix` is given in params", func() {
+			req.Params.Terraform.WorkspacePrefix = "team-a-"
+
+			runner := out.Runner{
+				SourceDir: workingDir,
+				LogWriter: &logWriter,
+			}
+			_, err := runner.Run(req)
+			Expect(err).To(MatchError(ContainSubstring("`workspace_prefix` can only be set in `source`")))
+		})
+
+		It("returns an error if `clone_from_env` is given with `delete_on_failure`", func() {
+			req.Params.Terraform.CloneFromEnv = "production"
+			req.Params.Terraform.DeleteOnFailure = true
+
+			runner := out.Runner{
+				SourceDir: workingDir,
+				LogWriter: &logWriter,
+			}
+			_, err := runner.Run(req)
+			Expect(err).To(MatchError(ContainSubstring("`clone_from_env` cannot be used with `delete_on_failure`")))
+		})
 	})
 
 	assertOutBehavior = func(outRequest models.OutRequest, expectedMetadata map[string]string) {
@@ -0,0 +1,192 @@
+package outputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	yamlConverter "github.com/ghodss/yaml"
+	"github.com/ljfranklin/terraform-resource/encoder"
+)
+
+// Supported `output_formats`, each format is written to `metadata.<format>`
+const (
+	YAMLFormat   = "yml"
+	EnvFormat    = "env"
+	TFVarsFormat = "tfvars.json"
+)
+
+var Formats = []string{YAMLFormat, EnvFormat, TFVarsFormat}
+
+var invalidEnvNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+func ValidateFormats(formats []string) error {
+	for _, format := range formats {
+		isKnownFormat := false
+		for _, knownFormat := range Formats {
+			if format == knownFormat {
+				isKnownFormat = true
+				break
+			}
+		}
+		if !isKnownFormat {
+			knownFormats := []string{}
+			for _, knownFormat := range Formats {
+				knownFormats = append(knownFormats, fmt.Sprintf("'%s'", knownFormat))
+			}
+			return fmt.Errorf(
+				"Unknown value for `output_formats`: '%s', Supported values: %s",
+				format,
+				strings.Join(knownFormats, ", "),
+			)
+		}
+	}
+	return nil
+}
+
+// Write encodes the output values, e.g. from `Result.RawOutput`, in the given format
+func Write(w io.Writer, format string, outputs map[string]interface{}) error {
+	switch format {
+	case YAMLFormat:
+		return writeYAML(w, outputs)
+	case EnvFormat:
+		return writeEnv(w, outputs)
+	case TFVarsFormat:
+		return writeTFVars(w, outputs)
+	default:
+		return ValidateFormats([]string{format})
+	}
+}
+
+// Flatten converts nested maps and lists into top-level keys joined by the
+// separator, e.g. `{"db": {"hosts": ["a"]}}` becomes `{"db.hosts.0": "a"}`.
+// Empty maps and lists are kept as is.
+func Flatten(outputs map[string]interface{}, separator string) map[string]interface{} {
+	flattened := map[string]interface{}{}
+	for key, value := range outputs {
+		flattenValue(flattened, key, value, separator)
+	}
+	return flattened
+}
+
+func flattenValue(flattened map[string]interface{}, key string, value interface{}, separator string) {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		if len(typedValue) == 0 {
+			flattened[key] = typedValue
+		}
+		for childKey, childValue := range typedValue {
+			flattenValue(flattened, key+separator+childKey, childValue, separator)
+		}
+	case []interface{}:
+		if len(typedValue) == 0 {
+			flattened[key] = typedValue
+		}
+		for i, childValue := range typedValue {
+			flattenValue(flattened, key+separator+strconv.Itoa(i), childValue, separator)
+		}
+	default:
+		flattened[key] = value
+	}
+}
+
+// ValueToString returns strings as is and other values as JSON
+func ValueToString(value interface{}) (string, error) {
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue, nil
+	case nil:
+		return "", nil
+	default:
+		jsonValue, err := json.Marshal(typedValue)
+		if err != nil {
+			return "", err
+		}
+		return string(jsonValue), nil
+	}
+}
+
+// WriteFiles writes each output to its own file in the dir, e.g. for
+// Concourse's `load_var` step. Strings are written as is and other values as JSON.
+func WriteFiles(dir string, outputs map[string]interface{}, perm os.FileMode) error {
+	dirPerm := os.FileMode(0755)
+	if perm&0044 == 0 {
+		dirPerm = 0700
+	}
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return err
+	}
+
+	for name, value := range outputs {
+		contents, err := ValueToString(value)
+		if err != nil {
+			return fmt.Errorf("Failed to encode output '%s': %s", name, err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), perm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeYAML(w io.Writer, outputs map[string]interface{}) error {
+	contents, err := yamlConverter.Marshal(outputs)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(contents)
+	return err
+}
+
+func writeTFVars(w io.Writer, outputs map[string]interface{}) error {
+	e := encoder.NewJSONEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(outputs)
+}
+
+// writeEnv writes one `name='value'` line per flattened output, nested keys
+// are joined with `_` and characters which are invalid in shell variable names
+// are replaced with `_`
+func writeEnv(w io.Writer, outputs map[string]interface{}) error {
+	flattened := Flatten(outputs, "_")
+
+	keys := []string{}
+	for key := range flattened {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	envNames := map[string]string{}
+	for _, key := range keys {
+		envName := invalidEnvNameChars.ReplaceAllString(key, "_")
+		if envName[0] >= '0' && envName[0] <= '9' {
+			envName = "_" + envName
+		}
+		if otherKey, ok := envNames[envName]; ok {
+			return fmt.Errorf("Outputs '%s' and '%s' both map to the env var '%s'", otherKey, key, envName)
+		}
+		envNames[envName] = key
+
+		value, err := ValueToString(flattened[key])
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintf(w, "%s=%s\n", envName, shellQuote(value)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
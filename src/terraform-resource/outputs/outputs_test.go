@@ -0,0 +1,168 @@
+package outputs_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/ljfranklin/terraform-resource/outputs"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Outputs", func() {
+
+	var rawOutputs map[string]interface{}
+
+	BeforeEach(func() {
+		err := json.Unmarshal([]byte(`{
+			"vpc_id": "vpc-123",
+			"instance_count": 3,
+			"enabled": true,
+			"password": "it's a secret",
+			"db": {
+				"hosts": ["10.0.0.1", "10.0.0.2"],
+				"port": 5432,
+				"tags": {}
+			},
+			"empty_list": [],
+			"nothing": null,
+			"some-output": "dashes"
+		}`), &rawOutputs)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	write := func(format string) string {
+		var buf bytes.Buffer
+		err := outputs.Write(&buf, format, rawOutputs)
+		Expect(err).ToNot(HaveOccurred())
+		return buf.String()
+	}
+
+	Describe("#ValidateFormats", func() {
+		It("accepts the supported formats", func() {
+			Expect(outputs.ValidateFormats([]string{"yml", "env", "tfvars.json"})).To(Succeed())
+			Expect(outputs.ValidateFormats(nil)).To(Succeed())
+		})
+
+		It("returns an error for unknown formats", func() {
+			err := outputs.ValidateFormats([]string{"yml", "toml"})
+			Expect(err).To(MatchError("Unknown value for `output_formats`: 'toml', Supported values: 'yml', 'env', 'tfvars.json'"))
+		})
+	})
+
+	Describe("#Flatten", func() {
+		It("joins nested keys with the separator", func() {
+			Expect(outputs.Flatten(rawOutputs, ".")).To(Equal(map[string]interface{}{
+				"vpc_id":         "vpc-123",
+				"instance_count": float64(3),
+				"enabled":        true,
+				"password":       "it's a secret",
+				"db.hosts.0":     "10.0.0.1",
+				"db.hosts.1":     "10.0.0.2",
+				"db.port":        float64(5432),
+				"db.tags":        map[string]interface{}{},
+				"empty_list":     []interface{}{},
+				"nothing":        nil,
+				"some-output":    "dashes",
+			}))
+		})
+	})
+
+	Describe("#Write", func() {
+		It("writes YAML", func() {
+			Expect(write(outputs.YAMLFormat)).To(Equal(`db:
+  hosts:
+  - 10.0.0.1
+  - 10.0.0.2
+  port: 5432
+  tags: {}
+empty_list: []
+enabled: true
+instance_count: 3
+nothing: null
+password: it's a secret
+some-output: dashes
+vpc_id: vpc-123
+`))
+		})
+
+		It("writes a shell sourceable env file", func() {
+			Expect(write(outputs.EnvFormat)).To(Equal(`db_hosts_0='10.0.0.1'
+db_hosts_1='10.0.0.2'
+db_port='5432'
+db_tags='{}'
+empty_list='[]'
+enabled='true'
+instance_count='3'
+nothing=''
+password='it'\''s a secret'
+some_output='dashes'
+vpc_id='vpc-123'
+`))
+		})
+
+		It("returns an error if two outputs map to the same env var", func() {
+			rawOutputs = map[string]interface{}{
+				"some-output": "a",
+				"some_output": "b",
+			}
+
+			err := outputs.Write(&bytes.Buffer{}, outputs.EnvFormat, rawOutputs)
+			Expect(err).To(MatchError("Outputs 'some-output' and 'some_output' both map to the env var 'some_output'"))
+		})
+
+		It("writes a tfvars.json file", func() {
+			var tfvars map[string]interface{}
+			Expect(json.Unmarshal([]byte(write(outputs.TFVarsFormat)), &tfvars)).To(Succeed())
+			Expect(tfvars).To(Equal(rawOutputs))
+		})
+	})
+
+	Describe("#WriteFiles", func() {
+		var tmpDir string
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = ioutil.TempDir(os.TempDir(), "terraform-resource-outputs-test")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(tmpDir)
+		})
+
+		readOutput := func(name string) string {
+			contents, err := ioutil.ReadFile(path.Join(tmpDir, "outputs", name))
+			Expect(err).ToNot(HaveOccurred())
+			return string(contents)
+		}
+
+		It("writes strings as is and other values as JSON", func() {
+			err := outputs.WriteFiles(path.Join(tmpDir, "outputs"), rawOutputs, 0644)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(readOutput("vpc_id")).To(Equal("vpc-123"))
+			Expect(readOutput("instance_count")).To(Equal("3"))
+			Expect(readOutput("enabled")).To(Equal("true"))
+			Expect(readOutput("db")).To(MatchJSON(`{"hosts": ["10.0.0.1", "10.0.0.2"], "port": 5432, "tags": {}}`))
+			Expect(readOutput("nothing")).To(Equal(""))
+		})
+
+		It("restricts access to the files if requested", func() {
+			err := outputs.WriteFiles(path.Join(tmpDir, "outputs"), rawOutputs, 0600)
+			Expect(err).ToNot(HaveOccurred())
+
+			dirInfo, err := os.Stat(path.Join(tmpDir, "outputs"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dirInfo.Mode().Perm()).To(Equal(os.FileMode(0700)))
+
+			fileInfo, err := os.Stat(path.Join(tmpDir, "outputs", "password"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fileInfo.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		})
+	})
+})
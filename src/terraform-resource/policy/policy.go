@@ -0,0 +1,230 @@
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	yamlConverter "github.com/ghodss/yaml"
+	"github.com/ljfranklin/terraform-resource/jsonplan"
+)
+
+const (
+	CreateAction  = jsonplan.CreateAction
+	UpdateAction  = jsonplan.UpdateAction
+	DeleteAction  = jsonplan.DeleteAction
+	ReplaceAction = jsonplan.ReplaceAction
+)
+
+// Policy is a set of rules evaluated against the JSON plan before it is
+// saved or applied. Resource types may contain glob patterns, e.g. `aws_iam_*`.
+type Policy struct {
+	DenyActions          []DenyActionRule  `json:"deny_actions,omitempty"`           // optional
+	AllowedResourceTypes []string          `json:"allowed_resource_types,omitempty"` // optional
+	RequiredTags         []RequiredTagRule `json:"required_tags,omitempty"`          // optional
+}
+
+type DenyActionRule struct {
+	Actions       []string `json:"actions"`
+	ResourceTypes []string `json:"resource_types,omitempty"` // optional, defaults to all types
+}
+
+type RequiredTagRule struct {
+	Tags          []string `json:"tags"`
+	ResourceTypes []string `json:"resource_types,omitempty"` // optional, defaults to all types
+}
+
+type Violation struct {
+	Address string
+	Message string
+}
+
+type ViolationsError struct {
+	PolicyFile string
+	Violations []Violation
+}
+
+func (e ViolationsError) Error() string {
+	lines := []string{
+		fmt.Sprintf("Plan violates %d rule(s) in policy file '%s':", len(e.Violations), e.PolicyFile),
+	}
+	for _, v := range e.Violations {
+		lines = append(lines, fmt.Sprintf("  - %s: %s", v.Address, v.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func ReadFile(policyPath string) (Policy, error) {
+	contents, err := ioutil.ReadFile(policyPath)
+	if err != nil {
+		return Policy{}, fmt.Errorf("Failed to read policy file at '%s': %s", policyPath, err)
+	}
+
+	jsonContents, err := yamlConverter.YAMLToJSON(contents)
+	if err != nil {
+		return Policy{}, fmt.Errorf("Failed to parse policy file at '%s': %s", policyPath, err)
+	}
+
+	p := Policy{}
+	decoder := json.NewDecoder(bytes.NewReader(jsonContents))
+	decoder.DisallowUnknownFields() // catch typos in rule names
+	if err = decoder.Decode(&p); err != nil {
+		return Policy{}, fmt.Errorf("Failed to parse policy file at '%s': %s", policyPath, err)
+	}
+
+	if err = p.Validate(); err != nil {
+		return Policy{}, fmt.Errorf("Invalid policy file at '%s': %s", policyPath, err)
+	}
+
+	return p, nil
+}
+
+func (p Policy) Validate() error {
+	knownActions := []string{CreateAction, UpdateAction, DeleteAction, ReplaceAction}
+	for i, rule := range p.DenyActions {
+		if len(rule.Actions) == 0 {
+			return fmt.Errorf("`deny_actions[%d].actions` must not be empty", i)
+		}
+		for _, action := range rule.Actions {
+			if !contains(knownActions, action) {
+				return fmt.Errorf("Unknown action '%s' in `deny_actions[%d]`, supported actions: %s", action, i, strings.Join(knownActions, ", "))
+			}
+		}
+		if err := validatePatterns(rule.ResourceTypes); err != nil {
+			return err
+		}
+	}
+
+	if err := validatePatterns(p.AllowedResourceTypes); err != nil {
+		return err
+	}
+
+	for i, rule := range p.RequiredTags {
+		if len(rule.Tags) == 0 {
+			return fmt.Errorf("`required_tags[%d].tags` must not be empty", i)
+		}
+		if err := validatePatterns(rule.ResourceTypes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Evaluate returns every violation in the plan, sorted by resource address.
+func (p Policy) Evaluate(plan jsonplan.Plan) []Violation {
+	violations := []Violation{}
+	for _, change := range plan.ResourceChanges {
+		if !change.IsManaged() {
+			continue
+		}
+		action := change.Action()
+		if action == jsonplan.NoOpAction {
+			continue
+		}
+		actions := []string{action}
+		if action == ReplaceAction {
+			// a replace deletes and recreates the resource, so it is denied by either rule
+			actions = append(actions, DeleteAction, CreateAction)
+		}
+
+		for _, rule := range p.DenyActions {
+			if !matchesType(rule.ResourceTypes, change.Type) {
+				continue
+			}
+			for _, action := range actions {
+				if contains(rule.Actions, action) {
+					violations = append(violations, Violation{
+						Address: change.Address,
+						Message: fmt.Sprintf("%s is denied for resource type '%s'", action, change.Type),
+					})
+					break
+				}
+			}
+		}
+
+		// deleting a resource of a disallowed type is always permitted
+		if len(p.AllowedResourceTypes) > 0 && !change.IsDelete() && !matchesType(p.AllowedResourceTypes, change.Type) {
+			violations = append(violations, Violation{
+				Address: change.Address,
+				Message: fmt.Sprintf("resource type '%s' is not in `allowed_resource_types`", change.Type),
+			})
+		}
+
+		if change.IsCreate() || change.IsReplace() {
+			for _, rule := range p.RequiredTags {
+				if !matchesType(rule.ResourceTypes, change.Type) {
+					continue
+				}
+				if missing := missingTags(change, rule.Tags); len(missing) > 0 {
+					violations = append(violations, Violation{
+						Address: change.Address,
+						Message: fmt.Sprintf("missing required tag(s): %s", strings.Join(missing, ", ")),
+					})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Address < violations[j].Address
+	})
+	return violations
+}
+
+func missingTags(change jsonplan.ResourceChange, requiredTags []string) []string {
+	// tags computed during apply can't be checked, so they are given the benefit of the doubt
+	if unknown, ok := change.Change.AfterUnknown.(map[string]interface{}); ok && unknown["tags"] == true {
+		return []string{}
+	}
+
+	tags := map[string]interface{}{}
+	if after, ok := change.Change.After.(map[string]interface{}); ok {
+		if afterTags, ok := after["tags"].(map[string]interface{}); ok {
+			tags = afterTags
+		}
+	}
+
+	missing := []string{}
+	for _, tag := range requiredTags {
+		if _, ok := tags[tag]; !ok {
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
+
+func matchesType(patterns []string, resourceType string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		// patterns are checked by Validate
+		if matched, _ := path.Match(pattern, resourceType); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid resource type pattern '%s': %s", pattern, err)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package policy_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/ljfranklin/terraform-resource/jsonplan"
+	"github.com/ljfranklin/terraform-resource/policy"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func resourceChange(address string, resourceType string, actions []string, after interface{}) jsonplan.ResourceChange {
+	return jsonplan.ResourceChange{
+		Address: address,
+		Mode:    jsonplan.ManagedMode,
+		Type:    resourceType,
+		Change: jsonplan.Change{
+			Actions: actions,
+			After:   after,
+		},
+	}
+}
+
+var _ = Describe("Policy", func() {
+
+	Describe("#Evaluate", func() {
+		var plan jsonplan.Plan
+
+		BeforeEach(func() {
+			plan = jsonplan.Plan{
+				ResourceChanges: []jsonplan.ResourceChange{
+					resourceChange("aws_s3_bucket.logs", "aws_s3_bucket", []string{"delete"}, nil),
+					resourceChange("aws_instance.web", "aws_instance", []string{"create"}, map[string]interface{}{
+						"tags": map[string]interface{}{"owner": "team-a"},
+					}),
+					resourceChange("aws_db_instance.main", "aws_db_instance", []string{"delete", "create"}, map[string]interface{}{}),
+					resourceChange("aws_iam_role.app", "aws_iam_role", []string{"update"}, map[string]interface{}{}),
+					resourceChange("random_id.unchanged", "random_id", []string{"no-op"}, map[string]interface{}{}),
+					{
+						Address: "data.aws_ami.ubuntu",
+						Mode:    "data",
+						Type:    "aws_ami",
+						Change:  jsonplan.Change{Actions: []string{"read"}},
+					},
+				},
+			}
+		})
+
+		It("returns no violations for an empty policy", func() {
+			Expect(policy.Policy{}.Evaluate(plan)).To(BeEmpty())
+		})
+
+		It("reports denied actions, treating replace as delete and create", func() {
+			p := policy.Policy{
+				DenyActions: []policy.DenyActionRule{
+					{Actions: []string{"delete"}},
+				},
+			}
+
+			Expect(p.Evaluate(plan)).To(Equal([]policy.Violation{
+				{Address: "aws_db_instance.main", Message: "delete is denied for resource type 'aws_db_instance'"},
+				{Address: "aws_s3_bucket.logs", Message: "delete is denied for resource type 'aws_s3_bucket'"},
+			}))
+		})
+
+		It("only denies actions for matching resource types", func() {
+			p := policy.Policy{
+				DenyActions: []policy.DenyActionRule{
+					{Actions: []string{"replace", "update"}, ResourceTypes: []string{"aws_iam_*"}},
+				},
+			}
+
+			Expect(p.Evaluate(plan)).To(Equal([]policy.Violation{
+				{Address: "aws_iam_role.app", Message: "update is denied for resource type 'aws_iam_role'"},
+			}))
+		})
+
+		It("reports resource types outside of the allow-list except for deletes", func() {
+			p := policy.Policy{
+				AllowedResourceTypes: []string{"aws_instance", "aws_db_*"},
+			}
+
+			Expect(p.Evaluate(plan)).To(Equal([]policy.Violation{
+				{Address: "aws_iam_role.app", Message: "resource type 'aws_iam_role' is not in `allowed_resource_types`"},
+			}))
+		})
+
+		It("reports missing tags on created and replaced resources", func() {
+			p := policy.Policy{
+				RequiredTags: []policy.RequiredTagRule{
+					{Tags: []string{"owner", "cost-center"}},
+				},
+			}
+
+			Expect(p.Evaluate(plan)).To(Equal([]policy.Violation{
+				{Address: "aws_db_instance.main", Message: "missing required tag(s): owner, cost-center"},
+				{Address: "aws_instance.web", Message: "missing required tag(s): cost-center"},
+			}))
+		})
+
+		It("skips tags that are unknown until apply", func() {
+			change := resourceChange("aws_instance.computed", "aws_instance", []string{"create"}, map[string]interface{}{})
+			change.Change.AfterUnknown = map[string]interface{}{"tags": true}
+			plan.ResourceChanges = []jsonplan.ResourceChange{change}
+
+			p := policy.Policy{
+				RequiredTags: []policy.RequiredTagRule{
+					{Tags: []string{"owner"}},
+				},
+			}
+
+			Expect(p.Evaluate(plan)).To(BeEmpty())
+		})
+	})
+
+	Describe("#ReadFile", func() {
+		var tmpDir string
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = ioutil.TempDir(os.TempDir(), "terraform-resource-policy-test")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			_ = os.RemoveAll(tmpDir)
+		})
+
+		writePolicy := func(contents string) string {
+			policyPath := path.Join(tmpDir, "policy.yml")
+			err := ioutil.WriteFile(policyPath, []byte(contents), 0644)
+			Expect(err).ToNot(HaveOccurred())
+			return policyPath
+		}
+
+		It("parses a valid policy", func() {
+			policyPath := writePolicy(`
+deny_actions:
+- actions: [delete]
+  resource_types: [aws_db_instance]
+allowed_resource_types: [aws_*]
+required_tags:
+- tags: [owner]
+`)
+
+			p, err := policy.ReadFile(policyPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p).To(Equal(policy.Policy{
+				DenyActions: []policy.DenyActionRule{
+					{Actions: []string{"delete"}, ResourceTypes: []string{"aws_db_instance"}},
+				},
+				AllowedResourceTypes: []string{"aws_*"},
+				RequiredTags: []policy.RequiredTagRule{
+					{Tags: []string{"owner"}},
+				},
+			}))
+		})
+
+		It("returns an error for unknown keys", func() {
+			policyPath := writePolicy("deny_action: []\n")
+
+			_, err := policy.ReadFile(policyPath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("deny_action"))
+		})
+
+		It("returns an error for unknown actions", func() {
+			policyPath := writePolicy("deny_actions:\n- actions: [destroy]\n")
+
+			_, err := policy.ReadFile(policyPath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unknown action 'destroy'"))
+		})
+
+		It("returns an error for invalid patterns", func() {
+			policyPath := writePolicy("allowed_resource_types: ['aws_[']\n")
+
+			_, err := policy.ReadFile(policyPath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("aws_["))
+		})
+	})
+
+	Describe("ViolationsError", func() {
+		It("lists every violation", func() {
+			err := policy.ViolationsError{
+				PolicyFile: "ci/policy.yml",
+				Violations: []policy.Violation{
+					{Address: "aws_s3_bucket.logs", Message: "delete is denied for resource type 'aws_s3_bucket'"},
+					{Address: "aws_iam_role.app", Message: "resource type 'aws_iam_role' is not in `allowed_resource_types`"},
+				},
+			}
+
+			Expect(err.Error()).To(Equal(`Plan violates 2 rule(s) in policy file 'ci/policy.yml':
+  - aws_s3_bucket.logs: delete is denied for resource type 'aws_s3_bucket'
+  - aws_iam_role.app: resource type 'aws_iam_role' is not in ` + "`allowed_resource_types`"))
+		})
+	})
+})
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// fs stores files in a local directory, mainly useful for testing
+type fs struct {
+	model Model
+}
+
+func NewFS(m Model) Storage {
+	return &fs{
+		model: m,
+	}
+}
+
+func (f *fs) Download(filename string, destination io.Writer) (Version, error) {
+	file, err := os.Open(f.localPath(filename))
+	if err != nil {
+		return Version{}, fmt.Errorf("Failed to open '%s': %s", filename, err)
+	}
+	defer file.Close()
+
+	if _, err = io.Copy(destination, file); err != nil {
+		return Version{}, fmt.Errorf("Failed to copy download to local file: %s", err)
+	}
+
+	return f.Version(filename)
+}
+
+func (f *fs) Upload(filename string, content io.Reader) (Version, error) {
+	localPath := f.localPath(filename)
+	if err := os.MkdirAll(filepath.Dir(localPath), 0700); err != nil {
+		return Version{}, fmt.Errorf("Failed to create directory for '%s': %s", filename, err)
+	}
+
+	// write to a temp file first so readers never see a partial upload
+	tmpFile, err := ioutil.TempFile(filepath.Dir(localPath), ".upload")
+	if err != nil {
+		return Version{}, fmt.Errorf("Failed to create temp file for '%s': %s", filename, err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err = io.Copy(tmpFile, content); err != nil {
+		tmpFile.Close()
+		return Version{}, fmt.Errorf("Failed to write '%s': %s", filename, err)
+	}
+	if err = tmpFile.Close(); err != nil {
+		return Version{}, fmt.Errorf("Failed to write '%s': %s", filename, err)
+	}
+	if err = os.Rename(tmpFile.Name(), localPath); err != nil {
+		return Version{}, fmt.Errorf("Failed to write '%s': %s", filename, err)
+	}
+
+	return f.Version(filename)
+}
+
+func (f *fs) Delete(filename string) error {
+	err := os.Remove(f.localPath(filename))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Failed to delete '%s': %s", filename, err)
+	}
+	return nil
+}
+
+func (f *fs) Version(filename string) (Version, error) {
+	fileInfo, err := os.Stat(f.localPath(filename))
+	if os.IsNotExist(err) {
+		return Version{}, nil // no versions exist
+	} else if err != nil {
+		return Version{}, fmt.Errorf("Failed to stat '%s': %s", filename, err)
+	}
+
+	version := Version{
+		LastModified: fileInfo.ModTime().UTC(),
+		StateFile:    filename,
+	}
+	return version, nil
+}
+
+func (f *fs) LatestVersion(filterRegex string) (Version, error) {
+	regex := regexp.MustCompile(filterRegex)
+
+	keys, err := f.List("")
+	if err != nil {
+		return Version{}, err
+	}
+
+	latest := Version{}
+	for _, key := range keys {
+		if !regex.MatchString(key) {
+			continue
+		}
+		version, err := f.Version(key)
+		if err != nil {
+			return Version{}, err
+		}
+		if latest.IsZero() || version.LastModified.After(latest.LastModified) {
+			latest = version
+		}
+	}
+	if latest.IsZero() {
+		return Version{}, nil // no versions exist
+	}
+
+	latest.StateFile = path.Base(latest.StateFile)
+	return latest, nil
+}
+
+func (f *fs) List(prefix string) ([]string, error) {
+	keys := []string{}
+	err := filepath.Walk(f.model.Directory, func(localPath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && localPath == f.model.Directory {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if fileInfo.IsDir() || strings.HasPrefix(fileInfo.Name(), ".upload") {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(f.model.Directory, localPath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relPath)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list files in '%s': %s", f.model.Directory, err)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func (f *fs) localPath(filename string) string {
+	return filepath.Join(f.model.Directory, filepath.FromSlash(filename))
+}
@@ -0,0 +1,94 @@
+package storage_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ljfranklin/terraform-resource/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FS", func() {
+
+	var (
+		tmpDir        string
+		storageDriver storage.Storage
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir(os.TempDir(), "terraform-resource-fs-test")
+		Expect(err).ToNot(HaveOccurred())
+
+		storageDriver = storage.BuildDriver(storage.Model{
+			Driver:    storage.FSDriver,
+			Directory: path.Join(tmpDir, "storage"),
+		})
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	It("uploads, downloads and deletes files", func() {
+		version, err := storageDriver.Version("some-dir/some-file")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version.IsZero()).To(BeTrue())
+
+		version, err = storageDriver.Upload("some-dir/some-file", strings.NewReader("some-contents"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version.StateFile).To(Equal("some-dir/some-file"))
+		Expect(version.LastModified.IsZero()).To(BeFalse())
+
+		var contents bytes.Buffer
+		_, err = storageDriver.Download("some-dir/some-file", &contents)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(contents.String()).To(Equal("some-contents"))
+
+		Expect(storageDriver.Delete("some-dir/some-file")).To(Succeed())
+		version, err = storageDriver.Version("some-dir/some-file")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version.IsZero()).To(BeTrue())
+
+		Expect(storageDriver.Delete("some-dir/some-file")).To(Succeed())
+	})
+
+	It("returns an error when downloading a missing file", func() {
+		_, err := storageDriver.Download("missing-file", &bytes.Buffer{})
+		Expect(err).To(MatchError(ContainSubstring("missing-file")))
+	})
+
+	It("lists files by prefix", func() {
+		keys, err := storageDriver.List("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(keys).To(BeEmpty())
+
+		for _, key := range []string{"env-b/file", "env-a/nested/file", "env-a/file", "other"} {
+			_, err = storageDriver.Upload(key, strings.NewReader(key))
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		keys, err = storageDriver.List("env-")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(keys).To(Equal([]string{"env-a/file", "env-a/nested/file", "env-b/file"}))
+	})
+
+	It("returns the latest file matching the regex", func() {
+		_, err := storageDriver.Upload("first.tfstate", strings.NewReader(""))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = storageDriver.Upload("ignored.tfplan", strings.NewReader(""))
+		Expect(err).ToNot(HaveOccurred())
+
+		version, err := storageDriver.LatestVersion(`.*\.tfstate$`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version.StateFile).To(Equal("first.tfstate"))
+
+		version, err = storageDriver.LatestVersion(`.*\.missing$`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(version.IsZero()).To(BeTrue())
+	})
+})
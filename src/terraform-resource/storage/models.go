@@ -8,6 +8,7 @@ import (
 
 const (
 	S3Driver = "s3"
+	FSDriver = "fs"
 )
 
 type Model struct {
@@ -24,6 +25,9 @@ type Model struct {
 	UseSigningV4         bool   `json:"use_signing_v4,omitempty"`         // optional
 	ServerSideEncryption string `json:"server_side_encryption,omitempty"` //optional
 	SSEKMSKeyId          string `json:"sse_kms_key_id,omitempty"`         //optional
+
+	// FS driver
+	Directory string `json:"directory,omitempty"`
 }
 
 type Version struct {
@@ -37,6 +41,7 @@ func (m Model) Validate() error {
 	knownDrivers := []string{
 		"",
 		S3Driver,
+		FSDriver,
 	}
 	isUnknownDriver := true
 	for _, driver := range knownDrivers {
@@ -72,6 +77,9 @@ func (m Model) Validate() error {
 			missingFields = append(missingFields, fmt.Sprintf("%s.secret_access_key", fieldPrefix))
 		}
 	}
+	if m.Driver == FSDriver && m.Directory == "" {
+		missingFields = append(missingFields, "storage.directory")
+	}
 
 	if len(missingFields) > 0 {
 		for i, value := range missingFields {
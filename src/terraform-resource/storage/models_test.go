@@ -45,6 +45,14 @@ var _ = Describe("Storage Models", func() {
 				}
 			})
 
+			It("returns error if fs storage directory is missing", func() {
+				model := storage.Model{
+					Driver: storage.FSDriver,
+				}
+				err := model.Validate()
+				Expect(err).To(MatchError("Missing fields: 'storage.directory'"))
+			})
+
 			It("returns error if storage driver is unknown", func() {
 				model := storage.Model{
 					Driver: "bad-driver",
@@ -26,3 +26,7 @@ func (n null) Version(key string) (Version, error) {
 func (n null) LatestVersion(filterRegex string) (Version, error) {
 	return Version{}, errors.New("Not Implemented")
 }
+
+func (n null) List(prefix string) ([]string, error) {
+	return nil, errors.New("Not Implemented")
+}
@@ -6,6 +6,7 @@ import (
 	"path"
 	"regexp"
 	"sort"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -185,6 +186,28 @@ func (s *s3) LatestVersion(filterRegex string) (Version, error) {
 	return version, nil
 }
 
+func (s *s3) List(prefix string) ([]string, error) {
+	keyPrefix := strings.TrimSuffix(s.model.BucketPath, "/") + "/"
+	params := &awss3.ListObjectsInput{
+		Bucket: aws.String(s.model.Bucket),
+		Prefix: aws.String(keyPrefix + prefix),
+	}
+
+	keys := []string{}
+	err := s.client.ListObjectsPages(params, func(page *awss3.ListObjectsOutput, lastPage bool) bool {
+		for _, file := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(*file.Key, keyPrefix))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ListObjects request failed.\nError: %s", err)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
 type ByLastModified []*awss3.Object
 
 func (a ByLastModified) Len() int           { return len(a) }
@@ -18,6 +18,7 @@ type Storage interface {
 	Delete(string) error
 	Version(string) (Version, error)
 	LatestVersion(string) (Version, error)
+	List(string) ([]string, error)
 }
 
 func BuildDriver(m Model) Storage {
@@ -30,6 +31,8 @@ func BuildDriver(m Model) Storage {
 	switch driverType {
 	case S3Driver:
 		storageDriver = NewS3(m)
+	case FSDriver:
+		storageDriver = NewFS(m)
 	default:
 		// calling model.Validate will throw error for this case
 		return null{}
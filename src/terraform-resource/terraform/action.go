@@ -3,13 +3,17 @@ package terraform
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"github.com/ljfranklin/terraform-resource/jsonplan"
 	"github.com/ljfranklin/terraform-resource/logger"
 	"github.com/ljfranklin/terraform-resource/models"
+	"github.com/ljfranklin/terraform-resource/outputs"
 )
 
 type Action struct {
@@ -21,8 +25,10 @@ type Action struct {
 }
 
 type Result struct {
-	Version models.Version
-	Output  map[string]map[string]interface{}
+	Version      models.Version
+	Output       map[string]map[string]interface{}
+	PlanSummary  *jsonplan.Summary // only set by Plan
+	PendingPlans []string          // only set by Plan
 }
 
 func (r Result) RawOutput() map[string]interface{} {
@@ -34,21 +40,73 @@ func (r Result) RawOutput() map[string]interface{} {
 	return outputs
 }
 
-func (r Result) SanitizedOutput() map[string]string {
-	output := map[string]string{}
+// SplitRawOutput returns the output values which are not marked as sensitive
+// and the sensitive output values separately
+func (r Result) SplitRawOutput() (map[string]interface{}, map[string]interface{}) {
+	outputs := map[string]interface{}{}
+	sensitiveOutputs := map[string]interface{}{}
 	for key, value := range r.Output {
 		if value["sensitive"] == true {
-			output[key] = "<sensitive>"
+			sensitiveOutputs[key] = value["value"]
 		} else {
-			jsonValue, err := json.Marshal(value["value"])
-			if err != nil {
-				jsonValue = []byte(fmt.Sprintf("Unable to parse output value for key '%s': %s", key, err))
-			}
+			outputs[key] = value["value"]
+		}
+	}
 
-			output[key] = strings.Trim(string(jsonValue), "\"")
+	return outputs, sensitiveOutputs
+}
+
+// Metadata returns the outputs shown in the Concourse UI sorted by name,
+// limited to `metadata_outputs` and truncated to `metadata_max_length`
+func (r Result) Metadata(model models.Terraform) []models.MetadataField {
+	sanitizedOutput := map[string]string{}
+	for key, value := range r.Output {
+		if !model.ShowsOutputInMetadata(key) {
+			continue
 		}
+		if value["sensitive"] == true {
+			sanitizedOutput[key] = "<sensitive>"
+		} else if model.MetadataFlatten {
+			flattened := outputs.Flatten(map[string]interface{}{key: value["value"]}, ".")
+			for flattenedKey, flattenedValue := range flattened {
+				sanitizedOutput[flattenedKey] = sanitizedValue(flattenedKey, flattenedValue)
+			}
+		} else {
+			sanitizedOutput[key] = sanitizedValue(key, value["value"])
+		}
+	}
+
+	keys := []string{}
+	for key := range sanitizedOutput {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	metadata := []models.MetadataField{}
+	for _, key := range keys {
+		metadata = append(metadata, models.MetadataField{
+			Name:  key,
+			Value: truncateMetadataValue(sanitizedOutput[key], model.MetadataMaxLength),
+		})
+	}
+	return metadata
+}
+
+func sanitizedValue(key string, value interface{}) string {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		jsonValue = []byte(fmt.Sprintf("Unable to parse output value for key '%s': %s", key, err))
 	}
-	return output
+
+	return strings.Trim(string(jsonValue), "\"")
+}
+
+func truncateMetadataValue(value string, maxLength int) string {
+	chars := []rune(value)
+	if maxLength <= 0 || len(chars) <= maxLength {
+		return value
+	}
+	return fmt.Sprintf("%s... (%d more characters truncated)", string(chars[:maxLength]), len(chars)-maxLength)
 }
 
 func LinkToThirdPartyPluginDir(sourceDir string) error {
@@ -96,36 +154,74 @@ func (a *Action) attemptApply() (Result, error) {
 	a.Logger.InfoSection("Terraform Apply")
 	defer a.Logger.EndSection()
 
+	var planMetadata PlanMetadata
 	if a.Model.PlanRun {
-		if err := a.Client.GetPlanFromBackend(a.planNameForEnv()); err != nil {
+		var err error
+		if planMetadata, err = a.Client.GetPlanFromBackend(a.planNameForEnv()); err != nil {
+			return Result{}, err
+		}
+
+		if err := verifyPlanChecksum(a.Model, planMetadata, a.Logger); err != nil {
+			return Result{}, err
+		}
+
+		if needsPlanReview(a.Model) {
+			if err := a.Client.JSONPlan(); err != nil {
+				return Result{}, err
+			}
+			if err := reviewPlan(a.Model, a.Logger); err != nil {
+				return Result{}, err
+			}
+		}
+	}
+
+	if a.Model.CloneFromEnv != "" {
+		if err := a.cloneFromEnv(); err != nil {
 			return Result{}, err
 		}
 	}
 
-	if err := a.Client.WorkspaceNewIfNotExists(a.EnvName); err != nil {
+	if err := a.Client.WorkspaceNewIfNotExists(a.workspace()); err != nil {
 		return Result{}, err
 	}
 
+	if a.Model.PlanRun {
+		if err := verifyPlanIsFresh(a.Client, a.Model, a.workspace(), planMetadata, a.Logger); err != nil {
+			return Result{}, err
+		}
+	}
+
 	if !a.Model.PlanRun {
-		if err := a.Client.Import(a.EnvName); err != nil {
+		if err := a.Client.Import(a.workspace()); err != nil {
+			return Result{}, err
+		}
+	}
+
+	if !a.Model.PlanRun && needsPlanReview(a.Model) {
+		if err := planAndReview(a.Client, a.Model, a.Logger); err != nil {
 			return Result{}, err
 		}
+		defer a.Client.SetModel(a.Model)
 	}
 
 	if err := a.Client.Apply(); err != nil {
 		return Result{}, err
 	}
 
-	stateVersion, err := a.Client.CurrentStateVersion(a.EnvName)
-	if err != nil {
+	if err := a.deletePendingPlans(); err != nil {
 		return Result{}, err
 	}
-	clientOutput, err := a.Client.Output(a.EnvName)
+
+	return a.resultFromState()
+}
+
+func (a *Action) resultFromState() (Result, error) {
+	stateVersion, err := a.Client.CurrentStateVersion(a.workspace())
 	if err != nil {
 		return Result{}, err
 	}
-
-	if err := a.deletePlanWorkspaceIfExists(); err != nil {
+	clientOutput, err := a.Client.Output(a.workspace())
+	if err != nil {
 		return Result{}, err
 	}
 
@@ -157,11 +253,11 @@ func (a *Action) attemptDestroy() (Result, error) {
 	a.Logger.WarnSection("Terraform Destroy")
 	defer a.Logger.EndSection()
 
-	if err := a.Client.WorkspaceSelect(a.EnvName); err != nil {
+	if err := a.Client.WorkspaceSelect(a.workspace()); err != nil {
 		return Result{}, err
 	}
 
-	if err := a.Client.Import(a.EnvName); err != nil {
+	if err := a.Client.Import(a.workspace()); err != nil {
 		return Result{}, err
 	}
 
@@ -169,11 +265,20 @@ func (a *Action) attemptDestroy() (Result, error) {
 		return Result{}, err
 	}
 
-	if err := a.Client.WorkspaceDelete(a.EnvName); err != nil {
+	if len(a.Model.Targets) > 0 {
+		// a targeted destroy leaves the rest of the env and its workspace in place
+		return a.resultFromState()
+	}
+
+	if err := a.Client.WorkspaceDelete(a.workspace()); err != nil {
 		return Result{}, err
 	}
 
-	if err := a.deletePlanWorkspaceIfExists(); err != nil {
+	if err := a.deletePendingPlans(); err != nil {
+		return Result{}, err
+	}
+
+	if err := deleteEnvMetadata(a.Client, a.workspace()); err != nil {
 		return Result{}, err
 	}
 
@@ -185,6 +290,40 @@ func (a *Action) attemptDestroy() (Result, error) {
 	}, nil
 }
 
+func (a *Action) Refresh() (Result, error) {
+	err := a.setup()
+	if err != nil {
+		return Result{}, err
+	}
+
+	result, err := a.attemptRefresh()
+	if err != nil {
+		a.Logger.Error("Failed To Run Terraform Refresh!")
+		err = fmt.Errorf("Refresh Error: %s", err)
+	}
+
+	if err == nil {
+		a.Logger.Success("Successfully Ran Terraform Refresh!")
+	}
+
+	return result, err
+}
+
+func (a *Action) attemptRefresh() (Result, error) {
+	a.Logger.InfoSection("Terraform Refresh")
+	defer a.Logger.EndSection()
+
+	if err := a.Client.WorkspaceSelect(a.workspace()); err != nil {
+		return Result{}, err
+	}
+
+	if err := a.Client.RefreshOnlyApply(); err != nil {
+		return Result{}, err
+	}
+
+	return a.resultFromState()
+}
+
 func (a *Action) Plan() (Result, error) {
 	err := a.setup()
 	if err != nil {
@@ -208,11 +347,11 @@ func (a *Action) attemptPlan() (Result, error) {
 	a.Logger.InfoSection("Terraform Plan")
 	defer a.Logger.EndSection()
 
-	if err := a.Client.WorkspaceNewIfNotExists(a.EnvName); err != nil {
+	if err := a.Client.WorkspaceNewIfNotExists(a.workspace()); err != nil {
 		return Result{}, err
 	}
 
-	if err := a.Client.Import(a.EnvName); err != nil {
+	if err := a.Client.Import(a.workspace()); err != nil {
 		return Result{}, err
 	}
 
@@ -226,59 +365,110 @@ func (a *Action) attemptPlan() (Result, error) {
 		return Result{}, err
 	}
 
-	if err = a.Client.SavePlanToBackend(a.planNameForEnv()); err != nil {
+	if err = reviewPlan(a.Model, a.Logger); err != nil {
 		return Result{}, err
 	}
 
-	return Result{
-		Output: map[string]map[string]interface{}{},
-		Version: models.Version{
-			EnvName:      a.EnvName,
-			PlanChecksum: checksum,
-		},
-	}, nil
+	planMetadata, err := newPlanMetadata(a.Client, a.workspace())
+	if err != nil {
+		return Result{}, err
+	}
+
+	if err = a.Client.SavePlanToBackend(a.planNameForEnv(), planMetadata); err != nil {
+		return Result{}, err
+	}
+
+	planNames, err := pendingPlans(a.Client, a.Model, a.workspace())
+	if err != nil {
+		return Result{}, err
+	}
+
+	return planResult(a.EnvName, checksum, a.Model, planNames)
 }
 
-func (a *Action) setup() error {
-	if err := LinkToThirdPartyPluginDir(a.SourceDir); err != nil {
+// cloneFromEnv seeds the workspace with the state of `clone_from_env`, an env
+// which already has state is left untouched so later puts apply as usual
+func (a *Action) cloneFromEnv() error {
+	sourceWorkspace := a.Model.WorkspaceName(a.Model.CloneFromEnv)
+	if sourceWorkspace == a.workspace() {
+		return fmt.Errorf("Failed to clone from env '%s': cannot clone an env into itself", a.Model.CloneFromEnv)
+	}
+
+	spaces, err := a.Client.WorkspaceList()
+	if err != nil {
 		return err
 	}
+	if !contains(spaces, sourceWorkspace) {
+		return fmt.Errorf("Failed to clone from env '%s': workspace '%s' does not exist", a.Model.CloneFromEnv, sourceWorkspace)
+	}
 
-	if err := copyOverrideFilesIntoSource(a.Model.OverrideFiles, a.Model.Source); err != nil {
+	targetExists := contains(spaces, a.workspace())
+	if targetExists {
+		version, err := a.Client.CurrentStateVersion(a.workspace())
+		if err != nil {
+			return err
+		}
+		if version != (StateVersion{}) {
+			a.Logger.Warn(fmt.Sprintf("Env '%s' already has state, skipping `clone_from_env`", a.EnvName))
+			return nil
+		}
+	}
+
+	rawState, err := a.Client.StatePull(sourceWorkspace)
+	if err != nil {
 		return err
 	}
+	version, err := ParseStateVersion(rawState)
+	if err != nil {
+		return err
+	}
+	if version == (StateVersion{}) {
+		return fmt.Errorf("Failed to clone from env '%s': env has no state", a.Model.CloneFromEnv)
+	}
 
-	if err := copyOverrideFilesIntoSourceDir(a.Model.ModuleOverrideFiles); err != nil {
+	tmpDir, err := ioutil.TempDir("", "tf-resource-clone")
+	if err != nil {
 		return err
 	}
+	defer os.RemoveAll(tmpDir)
 
-	if err := a.Client.InitWithBackend(); err != nil {
+	statePath := path.Join(tmpDir, "terraform.tfstate")
+	if err = ioutil.WriteFile(statePath, rawState, 0600); err != nil {
 		return err
 	}
 
-	return nil
+	a.Logger.Info(fmt.Sprintf("Cloning state of env '%s' into env '%s'", a.Model.CloneFromEnv, a.EnvName))
+	if targetExists {
+		// e.g. the workspace of a generated name is reserved before the apply
+		return a.Client.StatePush(a.workspace(), statePath)
+	}
+	return a.Client.WorkspaceNewFromExistingStateFile(a.workspace(), statePath)
 }
 
-func (a *Action) deletePlanWorkspaceIfExists() error {
-	workspaces, err := a.Client.WorkspaceList()
+func (a *Action) setup() error {
+	if err := LinkToThirdPartyPluginDir(a.SourceDir); err != nil {
+		return err
+	}
 
-	if err != nil {
+	if err := copyOverrideFilesIntoSource(a.Model.OverrideFiles, a.Model.Source); err != nil {
 		return err
 	}
 
-	workspaceExists := false
-	for _, space := range workspaces {
-		if space == a.planNameForEnv() {
-			workspaceExists = true
-		}
+	if err := copyOverrideFilesIntoSourceDir(a.Model.ModuleOverrideFiles); err != nil {
+		return err
 	}
 
-	if workspaceExists {
-		return a.Client.WorkspaceDeleteWithForce(a.planNameForEnv())
+	if err := a.Client.InitWithBackend(); err != nil {
+		return err
 	}
+
 	return nil
 }
 
+func (a *Action) deletePendingPlans() error {
+	return deletePendingPlans(a.Client, a.Model, a.workspace())
+}
+
 func copyOverrideFilesIntoSource(overrideFiles []string, sourceDir string) error {
 	for _, overridePath := range overrideFiles {
 		if fileInfo, err := os.Stat(overridePath); os.IsNotExist(err) {
@@ -349,5 +539,11 @@ func copyOverrideFilesIntoSourceDir(ModuleOverrideFiles []map[string]string) err
 }
 
 func (a *Action) planNameForEnv() string {
-	return fmt.Sprintf("%s-plan", a.EnvName)
+	return PlanWorkspaceName(a.workspace(), a.Model.PlanName)
+}
+
+// workspace returns the Terraform workspace which stores the env, the env
+// name itself is what users see in versions and the `name` file
+func (a *Action) workspace() string {
+	return a.Model.WorkspaceName(a.EnvName)
 }
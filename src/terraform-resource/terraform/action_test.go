@@ -0,0 +1,339 @@
+package terraform_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/ljfranklin/terraform-resource/logger"
+	"github.com/ljfranklin/terraform-resource/models"
+	"github.com/ljfranklin/terraform-resource/storage"
+	"github.com/ljfranklin/terraform-resource/terraform"
+	"github.com/ljfranklin/terraform-resource/terraform/terraformfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Result", func() {
+
+	Describe("#SplitRawOutput", func() {
+		It("separates sensitive output values", func() {
+			result := terraform.Result{
+				Output: map[string]map[string]interface{}{
+					"env_name": {"value": "staging", "sensitive": false},
+					"password": {"value": "super-secret", "sensitive": true},
+					"hosts":    {"value": []interface{}{"10.0.0.1"}},
+				},
+			}
+
+			rawOutputs, sensitiveOutputs := result.SplitRawOutput()
+			Expect(rawOutputs).To(Equal(map[string]interface{}{
+				"env_name": "staging",
+				"hosts":    []interface{}{"10.0.0.1"},
+			}))
+			Expect(sensitiveOutputs).To(Equal(map[string]interface{}{
+				"password": "super-secret",
+			}))
+		})
+	})
+
+	Describe("#Metadata", func() {
+		var result terraform.Result
+
+		BeforeEach(func() {
+			result = terraform.Result{
+				Output: map[string]map[string]interface{}{
+					"vpc_id":     {"value": "vpc-123"},
+					"password":   {"value": "super-secret", "sensitive": true},
+					"db":         {"value": map[string]interface{}{"hosts": []interface{}{"10.0.0.1"}, "port": float64(5432)}},
+					"kubeconfig": {"value": "apiVersion: v1\nclusters: []\n"},
+				},
+			}
+		})
+
+		It("returns all outputs sorted by name with sensitive values masked", func() {
+			Expect(result.Metadata(models.Terraform{})).To(Equal([]models.MetadataField{
+				{Name: "db", Value: `{"hosts":["10.0.0.1"],"port":5432}`},
+				{Name: "kubeconfig", Value: `apiVersion: v1\nclusters: []\n`},
+				{Name: "password", Value: "<sensitive>"},
+				{Name: "vpc_id", Value: "vpc-123"},
+			}))
+		})
+
+		It("only returns outputs matching `metadata_outputs`", func() {
+			model := models.Terraform{
+				MetadataOutputs: []string{"vpc_*", "password"},
+			}
+
+			Expect(result.Metadata(model)).To(Equal([]models.MetadataField{
+				{Name: "password", Value: "<sensitive>"},
+				{Name: "vpc_id", Value: "vpc-123"},
+			}))
+		})
+
+		It("truncates values longer than `metadata_max_length`", func() {
+			model := models.Terraform{
+				MetadataOutputs:   []string{"kubeconfig", "vpc_id"},
+				MetadataMaxLength: 10,
+			}
+
+			Expect(result.Metadata(model)).To(Equal([]models.MetadataField{
+				{Name: "kubeconfig", Value: `apiVersion... (20 more characters truncated)`},
+				{Name: "vpc_id", Value: "vpc-123"},
+			}))
+		})
+
+		It("flattens nested values into dotted keys with `metadata_flatten`", func() {
+			model := models.Terraform{
+				MetadataOutputs: []string{"db", "password"},
+				MetadataFlatten: true,
+			}
+
+			Expect(result.Metadata(model)).To(Equal([]models.MetadataField{
+				{Name: "db.hosts.0", Value: "10.0.0.1"},
+				{Name: "db.port", Value: "5432"},
+				{Name: "password", Value: "<sensitive>"},
+			}))
+		})
+	})
+})
+
+var _ = Describe("Action", func() {
+
+	Context("when `workspace_prefix` is set", func() {
+		var (
+			fakeClient *terraformfakes.FakeClient
+			action     terraform.Action
+		)
+
+		BeforeEach(func() {
+			fakeClient = &terraformfakes.FakeClient{}
+			fakeClient.WorkspaceListReturns([]string{"team-a-staging", "team-a-staging-plan"}, nil)
+			fakeClient.CurrentStateVersionReturns(terraform.StateVersion{Serial: 3, Lineage: "some-lineage"}, nil)
+			fakeClient.OutputReturns(map[string]map[string]interface{}{}, nil)
+
+			action = terraform.Action{
+				Client:  fakeClient,
+				Model:   models.Terraform{WorkspacePrefix: "team-a-"},
+				Logger:  logger.Logger{Sink: ioutil.Discard},
+				EnvName: "staging",
+			}
+		})
+
+		It("applies to the prefixed workspace but returns the unprefixed env name", func() {
+			result, err := action.Apply()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeClient.WorkspaceNewIfNotExistsArgsForCall(0)).To(Equal("team-a-staging"))
+			Expect(fakeClient.ImportArgsForCall(0)).To(Equal("team-a-staging"))
+			Expect(fakeClient.CurrentStateVersionArgsForCall(0)).To(Equal("team-a-staging"))
+			Expect(fakeClient.OutputArgsForCall(0)).To(Equal("team-a-staging"))
+			Expect(fakeClient.WorkspaceDeleteWithForceArgsForCall(0)).To(Equal("team-a-staging-plan"))
+			Expect(result.Version).To(Equal(models.Version{
+				EnvName: "staging",
+				Serial:  "3",
+				Lineage: "some-lineage",
+			}))
+		})
+
+		It("destroys the prefixed workspace", func() {
+			result, err := action.Destroy()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeClient.WorkspaceSelectArgsForCall(0)).To(Equal("team-a-staging"))
+			Expect(fakeClient.WorkspaceDeleteArgsForCall(0)).To(Equal("team-a-staging"))
+			Expect(result.Version.EnvName).To(Equal("staging"))
+		})
+	})
+
+	Context("when `plan_run` is set", func() {
+		var (
+			fakeClient *terraformfakes.FakeClient
+			action     terraform.Action
+			logs       *bytes.Buffer
+		)
+
+		BeforeEach(func() {
+			fakeClient = &terraformfakes.FakeClient{}
+			fakeClient.OutputReturns(map[string]map[string]interface{}{}, nil)
+			logs = &bytes.Buffer{}
+
+			action = terraform.Action{
+				Client:  fakeClient,
+				Model:   models.Terraform{PlanRun: true},
+				Logger:  logger.Logger{Sink: logs},
+				EnvName: "staging",
+			}
+		})
+
+		It("refuses to apply the stored plan without a checksum", func() {
+			fakeClient.GetPlanFromBackendReturns(terraform.PlanMetadata{CreatedAt: time.Now()}, nil)
+
+			_, err := action.Apply()
+			Expect(err).To(MatchError(ContainSubstring("`plan_run` requires `plan_checksum_file`")))
+			Expect(fakeClient.ApplyCallCount()).To(Equal(0))
+		})
+
+		It("warns that the plan is not verified with `allow_unverified_plan`", func() {
+			fakeClient.GetPlanFromBackendReturns(terraform.PlanMetadata{CreatedAt: time.Now()}, nil)
+			action.Model.AllowUnverifiedPlan = true
+
+			_, err := action.Apply()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(logs.String()).To(ContainSubstring("No `plan_checksum_file` given"))
+			Expect(fakeClient.ApplyCallCount()).To(Equal(1))
+		})
+
+		It("warns that a plan saved by an older version of the resource is not verified", func() {
+			_, err := action.Apply()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(logs.String()).To(ContainSubstring("No `plan_checksum_file` given"))
+			Expect(fakeClient.ApplyCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when `clone_from_env` is set", func() {
+		var (
+			fakeClient  *terraformfakes.FakeClient
+			action      terraform.Action
+			sourceState []byte
+			clonedState []byte
+		)
+
+		BeforeEach(func() {
+			sourceState = []byte(`{"version": 4, "serial": 12, "lineage": "prod-lineage", "resources": []}`)
+			clonedState = nil
+
+			fakeClient = &terraformfakes.FakeClient{}
+			fakeClient.WorkspaceListReturns([]string{"default", "team-a-prod"}, nil)
+			fakeClient.StatePullReturns(sourceState, nil)
+			fakeClient.CurrentStateVersionReturns(terraform.StateVersion{Serial: 13, Lineage: "prod-lineage"}, nil)
+			fakeClient.OutputReturns(map[string]map[string]interface{}{}, nil)
+			fakeClient.WorkspaceNewFromExistingStateFileStub = func(workspace string, statePath string) error {
+				var err error
+				clonedState, err = ioutil.ReadFile(statePath)
+				return err
+			}
+			fakeClient.StatePushStub = func(workspace string, statePath string) error {
+				var err error
+				clonedState, err = ioutil.ReadFile(statePath)
+				return err
+			}
+
+			action = terraform.Action{
+				Client: fakeClient,
+				Model: models.Terraform{
+					WorkspacePrefix: "team-a-",
+					CloneFromEnv:    "prod",
+				},
+				Logger:  logger.Logger{Sink: ioutil.Discard},
+				EnvName: "sandbox",
+			}
+		})
+
+		It("creates the workspace from the state of the other env before applying", func() {
+			result, err := action.Apply()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeClient.StatePullArgsForCall(0)).To(Equal("team-a-prod"))
+			workspace, _ := fakeClient.WorkspaceNewFromExistingStateFileArgsForCall(0)
+			Expect(workspace).To(Equal("team-a-sandbox"))
+			Expect(clonedState).To(Equal(sourceState))
+			Expect(fakeClient.ApplyCallCount()).To(Equal(1))
+			Expect(result.Version.EnvName).To(Equal("sandbox"))
+		})
+
+		It("pushes the state into a reserved workspace without state", func() {
+			fakeClient.WorkspaceListReturns([]string{"team-a-prod", "team-a-sandbox"}, nil)
+			fakeClient.CurrentStateVersionReturnsOnCall(0, terraform.StateVersion{}, nil)
+
+			_, err := action.Apply()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeClient.CurrentStateVersionArgsForCall(0)).To(Equal("team-a-sandbox"))
+			workspace, _ := fakeClient.StatePushArgsForCall(0)
+			Expect(workspace).To(Equal("team-a-sandbox"))
+			Expect(clonedState).To(Equal(sourceState))
+			Expect(fakeClient.WorkspaceNewFromExistingStateFileCallCount()).To(Equal(0))
+		})
+
+		It("leaves an env which already has state untouched", func() {
+			fakeClient.WorkspaceListReturns([]string{"team-a-prod", "team-a-sandbox"}, nil)
+
+			_, err := action.Apply()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeClient.StatePullCallCount()).To(Equal(0))
+			Expect(fakeClient.StatePushCallCount()).To(Equal(0))
+			Expect(fakeClient.WorkspaceNewFromExistingStateFileCallCount()).To(Equal(0))
+			Expect(fakeClient.ApplyCallCount()).To(Equal(1))
+		})
+
+		It("returns an error if the other env does not exist", func() {
+			fakeClient.WorkspaceListReturns([]string{"prod"}, nil)
+
+			_, err := action.Apply()
+			Expect(err).To(MatchError(ContainSubstring("Failed to clone from env 'prod': workspace 'team-a-prod' does not exist")))
+			Expect(fakeClient.ApplyCallCount()).To(Equal(0))
+		})
+
+		It("returns an error if the other env has no state", func() {
+			fakeClient.StatePullReturns([]byte{}, nil)
+
+			_, err := action.Apply()
+			Expect(err).To(MatchError(ContainSubstring("Failed to clone from env 'prod': env has no state")))
+			Expect(fakeClient.ApplyCallCount()).To(Equal(0))
+		})
+	})
+})
+
+var _ = Describe("MigratedFromStorageAction", func() {
+
+	var (
+		fakeClient *terraformfakes.FakeClient
+		action     terraform.MigratedFromStorageAction
+		tmpDir     string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "migrated-action-test")
+		Expect(err).ToNot(HaveOccurred())
+
+		fakeClient = &terraformfakes.FakeClient{}
+		fakeClient.WorkspaceListReturns([]string{"staging", "staging-meta", "staging-plan"}, nil)
+
+		action = terraform.MigratedFromStorageAction{
+			Client:  fakeClient,
+			Model:   models.Terraform{},
+			Logger:  logger.Logger{Sink: ioutil.Discard},
+			EnvName: "staging",
+			StateFile: storage.StateFile{
+				LocalPath:     path.Join(tmpDir, "terraform.tfstate"),
+				RemotePath:    "staging.tfstate",
+				StorageDriver: storage.NewFS(storage.Model{Directory: tmpDir}),
+			},
+		}
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	It("deletes the metadata workspace along with the env", func() {
+		_, err := action.Destroy()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeClient.WorkspaceDeleteArgsForCall(0)).To(Equal("staging"))
+		deleted := []string{}
+		for i := 0; i < fakeClient.WorkspaceDeleteWithForceCallCount(); i++ {
+			deleted = append(deleted, fakeClient.WorkspaceDeleteWithForceArgsForCall(i))
+		}
+		Expect(deleted).To(ConsistOf("staging-plan", "staging-meta"))
+	})
+})
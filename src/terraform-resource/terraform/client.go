@@ -3,10 +3,9 @@ package terraform
 import (
 	"bufio"
 	"bytes"
-	"compress/gzip"
 	"crypto/sha256"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -14,7 +13,9 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ljfranklin/terraform-resource/models"
 	"github.com/ljfranklin/terraform-resource/runner"
@@ -22,14 +23,20 @@ import (
 
 const defaultWorkspace = "default"
 
+// ErrWorkspaceExists is returned by WorkspaceNew if the workspace already exists
+var ErrWorkspaceExists = errors.New("Workspace already exists")
+
 //go:generate counterfeiter . Client
 
 type Client interface {
 	InitWithBackend() error
 	InitWithoutBackend() error
+	InitFromModule(string) error
 	Apply() error
+	RefreshOnlyApply() error
 	Destroy() error
 	Plan() (string, error)
+	RefreshOnlyPlan(string) (bool, error)
 	JSONPlan() error
 	Output(string) (map[string]map[string]interface{}, error)
 	OutputWithLegacyStorage() (map[string]map[string]interface{}, error)
@@ -38,14 +45,16 @@ type Client interface {
 	ImportWithLegacyStorage() error
 	WorkspaceList() ([]string, error)
 	WorkspaceNewFromExistingStateFile(string, string) error
+	WorkspaceNew(string) error
 	WorkspaceNewIfNotExists(string) error
 	WorkspaceSelect(string) error
 	WorkspaceDelete(string) error
 	WorkspaceDeleteWithForce(string) error
 	StatePull(string) ([]byte, error)
+	StatePush(string, string) error
 	CurrentStateVersion(string) (StateVersion, error)
-	SavePlanToBackend(string) error
-	GetPlanFromBackend(string) error
+	SavePlanToBackend(string, PlanMetadata) error
+	GetPlanFromBackend(string) (PlanMetadata, error)
 	SetModel(models.Terraform)
 }
 
@@ -59,6 +68,13 @@ type StateVersion struct {
 	Lineage string
 }
 
+// PlanMetadata is saved alongside a plan to detect stale plans during `plan_run`.
+// It is empty for plans saved by older versions of the resource.
+type PlanMetadata struct {
+	StateVersion StateVersion
+	CreatedAt    time.Time
+}
+
 func NewClient(model models.Terraform, logWriter io.Writer) Client {
 	return &client{
 		model:     model,
@@ -135,30 +151,16 @@ func (c *client) writeBackendConfig(outputDir string) (string, error) {
 	return backendPath, nil
 }
 
-func (c *client) writePlanProviderConfig(outputDir string, planContents, planContentsJSON []byte) error {
-	// GZip JSON plan to save space:
-	// https://github.com/ljfranklin/terraform-resource/issues/115#issuecomment-619525494
-	// Not gzipping the binary plan for now to avoid migration issues.
-
-	encodedPlan := base64.StdEncoding.EncodeToString(planContents)
-	escapedPlan, err := json.Marshal(encodedPlan)
+func (c *client) writePlanProviderConfig(outputDir string, planContents, planContentsJSON, planContentsText []byte, planMetadata PlanMetadata) error {
+	escapedPlan, err := c.encodeAndEscapePlan(planContents, models.PlanContent)
 	if err != nil {
 		return err
 	}
-
-	var encodedJSONBuffer bytes.Buffer
-	baseEncoder := base64.NewEncoder(base64.StdEncoding, &encodedJSONBuffer)
-	zw := gzip.NewWriter(baseEncoder)
-	if _, err = zw.Write(planContentsJSON); err != nil {
-		return err
-	}
-	if err = zw.Close(); err != nil {
-		return err
-	}
-	if err = baseEncoder.Close(); err != nil {
+	escapedJSONPlan, err := c.encodeAndEscapePlan(planContentsJSON, models.PlanContentJSON)
+	if err != nil {
 		return err
 	}
-	escapedJSONPlan, err := json.Marshal(encodedJSONBuffer.String())
+	escapedTextPlan, err := c.encodeAndEscapePlan(planContentsText, models.PlanContentText)
 	if err != nil {
 		return err
 	}
@@ -178,6 +180,9 @@ resource "stateful_string" "plan_output" {
 resource "stateful_string" "plan_output_json" {
   desired = %s
 }
+resource "stateful_string" "plan_output_text" {
+  desired = %s
+}
 output "%s" {
   sensitive = true
   value = stateful_string.plan_output.desired
@@ -186,7 +191,23 @@ output "%s" {
   sensitive = true
   value = stateful_string.plan_output_json.desired
 }
-`, escapedPlan, escapedJSONPlan, models.PlanContent, models.PlanContentJSON))
+output "%s" {
+  sensitive = true
+  value = stateful_string.plan_output_text.desired
+}
+output "%s" {
+  value = "%d"
+}
+output "%s" {
+  value = "%s"
+}
+output "%s" {
+  value = "%s"
+}
+`, escapedPlan, escapedJSONPlan, escapedTextPlan, models.PlanContent, models.PlanContentJSON, models.PlanContentText,
+		models.PlanStateSerial, planMetadata.StateVersion.Serial,
+		models.PlanLineage, planMetadata.StateVersion.Lineage,
+		models.PlanCreatedAt, planMetadata.CreatedAt.UTC().Format(time.RFC3339)))
 
 	configPath, err := filepath.Abs(path.Join(outputDir, "resource_plan_config.tf"))
 	if err != nil {
@@ -200,6 +221,14 @@ output "%s" {
 	return nil
 }
 
+func (c *client) encodeAndEscapePlan(contents []byte, outputName string) ([]byte, error) {
+	encodedPlan, err := EncodePlanOutput(contents, outputName, c.model)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encodedPlan)
+}
+
 func (c *client) writeBackendOverride(outputDir string) error {
 	backendPath := path.Join(outputDir, "resource_backend_override.tf")
 	backendContent := fmt.Sprintf(`terraform {
@@ -234,6 +263,31 @@ func (c *client) InitWithoutBackend() error {
 	return nil
 }
 
+// copies the module at the given address into the source dir,
+// used when the resource has no local copy of the configuration, e.g. in `check`
+func (c *client) InitFromModule(moduleSource string) error {
+	initArgs := []string{
+		"init",
+		"-input=false",
+		"-get=true",
+		"-backend=false",
+		fmt.Sprintf("-from-module=%s", moduleSource),
+	}
+	if c.model.PluginDir != "" {
+		initArgs = append(initArgs, fmt.Sprintf("-plugin-dir=%s", c.model.PluginDir))
+	}
+	initCmd, err := c.terraformCmd(initArgs, nil)
+	if err != nil {
+		return err
+	}
+
+	if output, err := initCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("terraform init -from-module command failed.\nError: %s\nOutput: %s", err, output)
+	}
+
+	return nil
+}
+
 // necessary to switch from backend to non-backend in `migrated_from_storage` code paths
 func (c *client) clearTerraformState() error {
 	configPath := path.Join(c.model.Source, ".terraform")
@@ -262,6 +316,10 @@ func (c *client) Apply() error {
 		for _, varFile := range c.model.ConvertedVarFiles {
 			applyArgs = append(applyArgs, fmt.Sprintf("-var-file=%s", varFile))
 		}
+
+		// a saved plan already contains any targets, Terraform rejects them on apply
+		applyArgs = append(applyArgs, c.targetArgs()...)
+		applyArgs = append(applyArgs, c.replaceArgs()...)
 	}
 
 	if c.model.Parallelism > 0 {
@@ -291,6 +349,45 @@ func (c *client) Apply() error {
 	return nil
 }
 
+// RefreshOnlyApply updates the statefile and outputs to match the real
+// infrastructure without proposing any changes to it.
+func (c *client) RefreshOnlyApply() error {
+	applyArgs := []string{
+		"apply",
+		"-refresh-only",
+		"-backup='-'",  // no need to backup state file
+		"-input=false", // do not prompt for inputs
+		"-auto-approve",
+	}
+
+	for _, varFile := range c.model.ConvertedVarFiles {
+		applyArgs = append(applyArgs, fmt.Sprintf("-var-file=%s", varFile))
+	}
+
+	applyArgs = append(applyArgs, c.targetArgs()...)
+
+	if c.model.Parallelism > 0 {
+		applyArgs = append(applyArgs, fmt.Sprintf("-parallelism=%d", c.model.Parallelism))
+	}
+
+	if c.model.LockTimeout != "" {
+		applyArgs = append(applyArgs, fmt.Sprintf("-lock-timeout=%s", c.model.LockTimeout))
+	}
+
+	applyCmd, err := c.terraformCmd(applyArgs, nil)
+	if err != nil {
+		return err
+	}
+	applyCmd.Stdout = c.logWriter
+	applyCmd.Stderr = c.logWriter
+	err = applyCmd.Run()
+	if err != nil {
+		return fmt.Errorf("Failed to run Terraform command: %s", err)
+	}
+
+	return nil
+}
+
 func (c *client) Destroy() error {
 	destroyArgs := []string{
 		"destroy",
@@ -307,6 +404,8 @@ func (c *client) Destroy() error {
 		destroyArgs = append(destroyArgs, fmt.Sprintf("-var-file=%s", varFile))
 	}
 
+	destroyArgs = append(destroyArgs, c.targetArgs()...)
+
 	destroyCmd, err := c.terraformCmd(destroyArgs, nil)
 	if err != nil {
 		return err
@@ -337,6 +436,9 @@ func (c *client) Plan() (string, error) {
 		planArgs = append(planArgs, fmt.Sprintf("-var-file=%s", varFile))
 	}
 
+	planArgs = append(planArgs, c.targetArgs()...)
+	planArgs = append(planArgs, c.replaceArgs()...)
+
 	planCmd, err := c.terraformCmd(planArgs, nil)
 	if err != nil {
 		return "", err
@@ -348,7 +450,11 @@ func (c *client) Plan() (string, error) {
 		return "", fmt.Errorf("Failed to run Terraform command: %s", err)
 	}
 
-	planFile, err := os.Open(c.model.PlanFileLocalPath)
+	return planFileChecksum(c.model.PlanFileLocalPath)
+}
+
+func planFileChecksum(planPath string) (string, error) {
+	planFile, err := os.Open(planPath)
 	if err != nil {
 		return "", fmt.Errorf("Failed to open planfile: %s", err)
 	}
@@ -362,6 +468,45 @@ func (c *client) Plan() (string, error) {
 	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
 
+// RefreshOnlyPlan returns true if the real infrastructure no longer matches
+// the statefile for the given env.
+func (c *client) RefreshOnlyPlan(envName string) (bool, error) {
+	planArgs := []string{
+		"plan",
+		"-refresh-only",
+		"-detailed-exitcode",
+		"-input=false", // do not prompt for inputs
+		fmt.Sprintf("-out=%s", c.model.PlanFileLocalPath),
+	}
+
+	if c.model.LockTimeout != "" {
+		planArgs = append(planArgs, fmt.Sprintf("-lock-timeout=%s", c.model.LockTimeout))
+	}
+
+	for _, varFile := range c.model.ConvertedVarFiles {
+		planArgs = append(planArgs, fmt.Sprintf("-var-file=%s", varFile))
+	}
+
+	planCmd, err := c.terraformCmd(planArgs, []string{
+		fmt.Sprintf("TF_WORKSPACE=%s", envName),
+	})
+	if err != nil {
+		return false, err
+	}
+	planCmd.Stdout = c.logWriter
+	planCmd.Stderr = c.logWriter
+	err = planCmd.Run()
+	if err == nil {
+		return false, nil
+	}
+
+	// -detailed-exitcode returns 2 when the plan succeeded and contains changes
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 2 {
+		return true, nil
+	}
+	return false, fmt.Errorf("Failed to run Terraform command: %s", err)
+}
+
 func (c *client) JSONPlan() error {
 	// terraform show -json tfplan.binary > tfplan.json
 	planArgs := []string{
@@ -387,6 +532,25 @@ func (c *client) JSONPlan() error {
 	return nil
 }
 
+func (c *client) renderPlan() ([]byte, error) {
+	showArgs := []string{
+		"show",
+		"-no-color",
+		c.model.PlanFileLocalPath,
+	}
+
+	showCmd, err := c.terraformCmd(showArgs, nil)
+	if err != nil {
+		return nil, err
+	}
+	rawOutput, err := showCmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to render plan.\nError: %s\nOutput: %s", err, rawOutput)
+	}
+
+	return rawOutput, nil
+}
+
 func (c *client) Output(envName string) (map[string]map[string]interface{}, error) {
 	outputArgs := []string{
 		"output",
@@ -582,6 +746,28 @@ func (c *client) WorkspaceSelect(envName string) error {
 	return nil
 }
 
+// WorkspaceNew creates the workspace and returns ErrWorkspaceExists if it
+// already exists, e.g. because it was created concurrently by another build
+func (c *client) WorkspaceNew(envName string) error {
+	cmd, err := c.terraformCmd([]string{
+		"workspace",
+		"new",
+		envName,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if strings.Contains(string(output), "already exists") {
+			return ErrWorkspaceExists
+		}
+		return fmt.Errorf("Error running `workspace new`: %s, Output: %s", err, output)
+	}
+
+	return nil
+}
+
 func (c *client) WorkspaceNewIfNotExists(envName string) error {
 	workspaces, err := c.WorkspaceList()
 
@@ -600,20 +786,11 @@ func (c *client) WorkspaceNewIfNotExists(envName string) error {
 		return c.WorkspaceSelect(envName)
 	}
 
-	cmd, err := c.terraformCmd([]string{
-		"workspace",
-		"new",
-		envName,
-	}, nil)
-	if err != nil {
-		return err
-	}
-
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("Error running `workspace new`: %s, Output: %s", err, output)
+	err = c.WorkspaceNew(envName)
+	if err == ErrWorkspaceExists {
+		return c.WorkspaceSelect(envName)
 	}
-
-	return nil
+	return err
 }
 
 func (c *client) WorkspaceNewFromExistingStateFile(envName string, localStateFilePath string) error {
@@ -716,14 +893,44 @@ func (c *client) StatePull(envName string) ([]byte, error) {
 	return rawOutput, nil
 }
 
+// StatePush overwrites the state of the workspace with the given state file
+func (c *client) StatePush(envName string, localStateFilePath string) error {
+	cmd, err := c.terraformCmd([]string{
+		"state",
+		"push",
+		"-force",
+		localStateFilePath,
+	}, []string{
+		fmt.Sprintf("TF_WORKSPACE=%s", envName),
+	})
+	if err != nil {
+		return err
+	}
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("Error running `state push`: %s, Output: %s", err, output)
+	}
+
+	return nil
+}
+
 func (c *client) CurrentStateVersion(envName string) (StateVersion, error) {
 	rawState, err := c.StatePull(envName)
 	if err != nil {
 		return StateVersion{}, err
 	}
+	return ParseStateVersion(rawState)
+}
+
+// ParseStateVersion returns the serial and lineage of a statefile as output by `terraform state pull`
+func ParseStateVersion(rawState []byte) (StateVersion, error) {
+	if len(bytes.TrimSpace(rawState)) == 0 {
+		// workspace has been created but nothing has been applied yet
+		return StateVersion{}, nil
+	}
 
 	tfState := map[string]interface{}{}
-	if err = json.Unmarshal(rawState, &tfState); err != nil {
+	if err := json.Unmarshal(rawState, &tfState); err != nil {
 		return StateVersion{}, fmt.Errorf("Failed to unmarshal JSON output.\nError: %s\nOutput: %s", err, rawState)
 	}
 
@@ -742,7 +949,33 @@ func (c *client) CurrentStateVersion(envName string) (StateVersion, error) {
 	}, nil
 }
 
-func (c *client) SavePlanToBackend(planEnvName string) error {
+// StateResourceCount returns the number of managed resource instances in a
+// statefile as output by `terraform state pull`, data sources are not counted
+func StateResourceCount(rawState []byte) (int, error) {
+	if len(bytes.TrimSpace(rawState)) == 0 {
+		return 0, nil
+	}
+
+	tfState := struct {
+		Resources []struct {
+			Mode      string            `json:"mode"`
+			Instances []json.RawMessage `json:"instances"`
+		} `json:"resources"`
+	}{}
+	if err := json.Unmarshal(rawState, &tfState); err != nil {
+		return 0, fmt.Errorf("Failed to unmarshal JSON output.\nError: %s\nOutput: %s", err, rawState)
+	}
+
+	count := 0
+	for _, resource := range tfState.Resources {
+		if resource.Mode == "managed" {
+			count += len(resource.Instances)
+		}
+	}
+	return count, nil
+}
+
+func (c *client) SavePlanToBackend(planEnvName string, planMetadata PlanMetadata) error {
 	planContents, err := ioutil.ReadFile(c.model.PlanFileLocalPath)
 	if err != nil {
 		return err
@@ -751,6 +984,25 @@ func (c *client) SavePlanToBackend(planEnvName string) error {
 	if err != nil {
 		return err
 	}
+	// rendered now as `get` has no configuration to initialize providers for `terraform show`
+	planContentsText, err := c.renderPlan()
+	if err != nil {
+		return err
+	}
+
+	if c.model.UsesPlanStorage() {
+		checksum, err := planFileChecksum(c.model.PlanFileLocalPath)
+		if err != nil {
+			return err
+		}
+		return NewPlanStore(c.model).Save(planEnvName, StoredPlan{
+			Checksum: checksum,
+			Plan:     planContents,
+			JSONPlan: planContentsJSON,
+			TextPlan: planContentsText,
+			Metadata: planMetadata,
+		})
+	}
 
 	tmpDir, err := ioutil.TempDir("", "tf-resource-plan")
 	if err != nil {
@@ -789,7 +1041,7 @@ func (c *client) SavePlanToBackend(planEnvName string) error {
 	// The /tmp/tf-plan.log file can contain credentials, so we tell the user to
 	// SSH into the container to view it rather than printing logs directly to the build logs.
 	errPrefix := "Failed to upload plan file to TF backend. Use `fly intercept` to SSH into this container and view %s for more logs. Error: %s"
-	err = c.writePlanProviderConfig(tmpDir, planContents, planContentsJSON)
+	err = c.writePlanProviderConfig(tmpDir, planContents, planContentsJSON, planContentsText, planMetadata)
 	if err != nil {
 		return fmt.Errorf(errPrefix, logPath, err)
 	}
@@ -812,39 +1064,79 @@ func (c *client) SavePlanToBackend(planEnvName string) error {
 	return nil
 }
 
-func (c *client) GetPlanFromBackend(planEnvName string) error {
+func (c *client) GetPlanFromBackend(planEnvName string) (PlanMetadata, error) {
+	if c.model.UsesPlanStorage() {
+		// the reviewed plan is fetched by its checksum so a newer plan is never applied in its place
+		plan, err := NewPlanStore(c.model).Get(planEnvName, c.model.PlanChecksum)
+		if err != nil {
+			return PlanMetadata{}, err
+		}
+		if err = ioutil.WriteFile(c.model.PlanFileLocalPath, plan.Plan, 0755); err != nil {
+			return PlanMetadata{}, err
+		}
+		return plan.Metadata, nil
+	}
+
 	if err := c.WorkspaceSelect(planEnvName); err != nil {
-		return err
+		return PlanMetadata{}, err
 	}
 
 	outputs, err := c.Output(planEnvName)
 	if err != nil {
-		return err
-	}
-
-	var encodedPlan string
-	if val, ok := outputs[models.PlanContent]; ok {
-		encodedPlan = val["value"].(string)
-	} else {
-		return fmt.Errorf("state has no output for key %s", models.PlanContent)
+		return PlanMetadata{}, err
 	}
 
-	decodedPlan, err := base64.StdEncoding.DecodeString(encodedPlan)
+	decodedPlan, err := DecodePlanOutput(outputs, models.PlanContent, c.model)
 	if err != nil {
-		return err
+		return PlanMetadata{}, err
 	}
 
 	if err = ioutil.WriteFile(c.model.PlanFileLocalPath, []byte(decodedPlan), 0755); err != nil {
-		return err
+		return PlanMetadata{}, err
 	}
 
-	return nil
+	return planMetadataFromOutputs(outputs)
+}
+
+func planMetadataFromOutputs(outputs map[string]map[string]interface{}) (PlanMetadata, error) {
+	createdAt, ok := outputs[models.PlanCreatedAt]
+	if !ok {
+		return PlanMetadata{}, nil
+	}
+
+	planMetadata := PlanMetadata{}
+	var err error
+	if planMetadata.CreatedAt, err = time.Parse(time.RFC3339, fmt.Sprintf("%v", createdAt["value"])); err != nil {
+		return PlanMetadata{}, fmt.Errorf("Failed to parse output %s: %s", models.PlanCreatedAt, err)
+	}
+	if planMetadata.StateVersion.Serial, err = strconv.Atoi(fmt.Sprintf("%v", outputs[models.PlanStateSerial]["value"])); err != nil {
+		return PlanMetadata{}, fmt.Errorf("Failed to parse output %s: %s", models.PlanStateSerial, err)
+	}
+	planMetadata.StateVersion.Lineage = fmt.Sprintf("%v", outputs[models.PlanLineage]["value"])
+
+	return planMetadata, nil
 }
 
 func (c *client) SetModel(model models.Terraform) {
 	c.model = model
 }
 
+func (c *client) targetArgs() []string {
+	args := []string{}
+	for _, target := range c.model.Targets {
+		args = append(args, fmt.Sprintf("-target=%s", target))
+	}
+	return args
+}
+
+func (c *client) replaceArgs() []string {
+	args := []string{}
+	for _, address := range c.model.Replace {
+		args = append(args, fmt.Sprintf("-replace=%s", address))
+	}
+	return args
+}
+
 func (c *client) resourceExists(tfID string, envName string) (bool, error) {
 	cmd, err := c.terraformCmd([]string{
 		"state",
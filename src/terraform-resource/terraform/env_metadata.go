@@ -0,0 +1,230 @@
+package terraform
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ljfranklin/terraform-resource/models"
+)
+
+const envMetadataOutput = "env_metadata"
+
+// EnvMetadata is recorded by `put` in the `<env>-meta` workspace when a `ttl`
+// or `labels` are given, `action: reap` destroys envs once they have expired
+type EnvMetadata struct {
+	CreatedAt    time.Time         `json:"created_at"`
+	ExpiresAt    time.Time         `json:"expires_at"`
+	TeamName     string            `json:"team_name,omitempty"`
+	PipelineName string            `json:"pipeline_name,omitempty"`
+	JobName      string            `json:"job_name,omitempty"`
+	BuildName    string            `json:"build_name,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+func (m EnvMetadata) IsExpired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && m.ExpiresAt.Before(now)
+}
+
+// MetadataWorkspaceName returns the workspace used to store the EnvMetadata of the given env
+func MetadataWorkspaceName(envName string) string {
+	return fmt.Sprintf("%s-meta", envName)
+}
+
+// EnvWorkspaces returns the workspaces which hold an env, skipping the
+// metadata workspaces which belong to another workspace in the list and all
+// plan workspaces, including those of a generated name released after `plan_only`
+func EnvWorkspaces(workspaces []string) []string {
+	belongsToEnv := map[string]bool{}
+	for _, envName := range workspaces {
+		belongsToEnv[MetadataWorkspaceName(envName)] = true
+	}
+
+	envs := []string{}
+	for _, space := range workspaces {
+		if !belongsToEnv[space] && !isPlanWorkspace(space) {
+			envs = append(envs, space)
+		}
+	}
+	return envs
+}
+
+func isPlanWorkspace(workspace string) bool {
+	return strings.HasSuffix(workspace, PlanWorkspaceName("", DefaultPlanName)) ||
+		strings.Contains(workspace, PlanWorkspaceName("", DefaultPlanName)+".")
+}
+
+// RecordEnvMetadata saves and returns the metadata of the env, the creation
+// details of an env which already has metadata are kept and only the expiry
+// and labels are updated if given
+func RecordEnvMetadata(client Client, model models.Terraform, envName string, metadata EnvMetadata) (EnvMetadata, error) {
+	workspace := model.WorkspaceName(envName)
+	metaWorkspace := MetadataWorkspaceName(workspace)
+
+	spaces, err := client.WorkspaceList()
+	if err != nil {
+		return EnvMetadata{}, err
+	}
+	if contains(spaces, metaWorkspace) {
+		existing, err := ReadEnvMetadata(client, workspace)
+		if err != nil {
+			return EnvMetadata{}, err
+		}
+		if !existing.CreatedAt.IsZero() {
+			if !metadata.ExpiresAt.IsZero() {
+				existing.ExpiresAt = metadata.ExpiresAt
+			}
+			if metadata.Labels != nil {
+				existing.Labels = metadata.Labels
+			}
+			metadata = existing
+		}
+	}
+
+	// the metadata state is stamped with the Terraform version of the env's own state
+	rawState, err := client.StatePull(workspace)
+	if err != nil {
+		return EnvMetadata{}, err
+	}
+	envState := struct {
+		TerraformVersion string `json:"terraform_version"`
+	}{}
+	// an env without state yet, e.g. a reserved name, has no Terraform version to copy
+	if len(bytes.TrimSpace(rawState)) > 0 {
+		if err = json.Unmarshal(rawState, &envState); err != nil {
+			return EnvMetadata{}, fmt.Errorf("Failed to unmarshal state of '%s': %s", workspace, err)
+		}
+	}
+
+	stateContents, err := envMetadataState(metadata, envState.TerraformVersion)
+	if err != nil {
+		return EnvMetadata{}, err
+	}
+
+	tmpDir, err := ioutil.TempDir("", "tf-resource-env-metadata")
+	if err != nil {
+		return EnvMetadata{}, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	statePath := path.Join(tmpDir, "terraform.tfstate")
+	if err = ioutil.WriteFile(statePath, stateContents, 0600); err != nil {
+		return EnvMetadata{}, err
+	}
+
+	if err = client.WorkspaceNewIfNotExists(metaWorkspace); err != nil {
+		return EnvMetadata{}, err
+	}
+	if err = client.StatePush(metaWorkspace, statePath); err != nil {
+		return EnvMetadata{}, fmt.Errorf("Failed to record metadata for '%s': %s", envName, err)
+	}
+
+	if err = client.WorkspaceSelect(workspace); err != nil {
+		return EnvMetadata{}, err
+	}
+
+	return metadata, nil
+}
+
+// ReadEnvMetadata returns the metadata recorded for the env stored in the given
+// workspace, the caller must check that its metadata workspace exists
+func ReadEnvMetadata(client Client, workspace string) (EnvMetadata, error) {
+	metaWorkspace := MetadataWorkspaceName(workspace)
+	rawState, err := client.StatePull(metaWorkspace)
+	if err != nil {
+		return EnvMetadata{}, err
+	}
+	if len(bytes.TrimSpace(rawState)) == 0 {
+		return EnvMetadata{}, nil
+	}
+
+	state := struct {
+		Outputs map[string]struct {
+			Value string `json:"value"`
+		} `json:"outputs"`
+	}{}
+	if err = json.Unmarshal(rawState, &state); err != nil {
+		return EnvMetadata{}, fmt.Errorf("Failed to unmarshal state of '%s': %s", metaWorkspace, err)
+	}
+
+	output, ok := state.Outputs[envMetadataOutput]
+	if !ok {
+		return EnvMetadata{}, nil
+	}
+
+	metadata := EnvMetadata{}
+	if err = json.Unmarshal([]byte(output.Value), &metadata); err != nil {
+		return EnvMetadata{}, fmt.Errorf("Failed to unmarshal metadata in '%s': %s", metaWorkspace, err)
+	}
+	return metadata, nil
+}
+
+// deleteEnvMetadata removes the metadata workspace of the env if there is one
+func deleteEnvMetadata(client Client, workspace string) error {
+	spaces, err := client.WorkspaceList()
+	if err != nil {
+		return err
+	}
+	metaWorkspace := MetadataWorkspaceName(workspace)
+	if !contains(spaces, metaWorkspace) {
+		return nil
+	}
+	return client.WorkspaceDeleteWithForce(metaWorkspace)
+}
+
+// envMetadataState returns a statefile without resources which holds the
+// metadata as an output, so it can be stored in any backend
+func envMetadataState(metadata EnvMetadata, terraformVersion string) ([]byte, error) {
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	lineage, err := newLineage()
+	if err != nil {
+		return nil, err
+	}
+
+	state := map[string]interface{}{
+		"version": 4,
+		"serial":  1,
+		"lineage": lineage,
+		"outputs": map[string]interface{}{
+			envMetadataOutput: map[string]interface{}{
+				"value": string(metadataJSON),
+				"type":  "string",
+			},
+		},
+		"resources": []interface{}{},
+	}
+	if terraformVersion != "" {
+		state["terraform_version"] = terraformVersion
+	}
+	return json.MarshalIndent(state, "", "  ")
+}
+
+// newLineage returns a random UUID as generated by Terraform for new states
+func newLineage() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
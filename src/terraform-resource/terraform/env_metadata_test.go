@@ -0,0 +1,167 @@
+package terraform_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/ljfranklin/terraform-resource/models"
+	"github.com/ljfranklin/terraform-resource/terraform"
+	"github.com/ljfranklin/terraform-resource/terraform/terraformfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EnvMetadata", func() {
+
+	Describe("#EnvWorkspaces", func() {
+		It("skips the plan and metadata workspaces of other envs", func() {
+			workspaces := []string{
+				"default",
+				"staging",
+				"staging-meta",
+				"staging-plan",
+				"staging-plan.feature-a",
+				"orphaned-meta",
+				"released-plan",
+				"released-plan.feature-a",
+			}
+
+			Expect(terraform.EnvWorkspaces(workspaces)).To(Equal([]string{
+				"default",
+				"staging",
+				"orphaned-meta",
+			}))
+		})
+	})
+
+	Describe("#IsExpired", func() {
+		It("returns true once the expiry has passed", func() {
+			now := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+
+			Expect(terraform.EnvMetadata{ExpiresAt: now.Add(-time.Minute)}.IsExpired(now)).To(BeTrue())
+			Expect(terraform.EnvMetadata{ExpiresAt: now.Add(time.Minute)}.IsExpired(now)).To(BeFalse())
+			Expect(terraform.EnvMetadata{}.IsExpired(now)).To(BeFalse())
+		})
+	})
+
+	Describe("#RecordEnvMetadata", func() {
+		var (
+			fakeClient  *terraformfakes.FakeClient
+			pushedState map[string]interface{}
+			createdAt   time.Time
+			expiresAt   time.Time
+		)
+
+		BeforeEach(func() {
+			createdAt = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			expiresAt = createdAt.Add(72 * time.Hour)
+
+			fakeClient = &terraformfakes.FakeClient{}
+			fakeClient.WorkspaceListReturns([]string{"team-a-staging"}, nil)
+			fakeClient.StatePullReturns([]byte(`{"version": 4, "terraform_version": "1.1.0"}`), nil)
+			fakeClient.StatePushStub = func(workspace string, statePath string) error {
+				contents, err := ioutil.ReadFile(statePath)
+				Expect(err).ToNot(HaveOccurred())
+				pushedState = map[string]interface{}{}
+				return json.Unmarshal(contents, &pushedState)
+			}
+		})
+
+		It("pushes the metadata as an output into the metadata workspace", func() {
+			metadata, err := terraform.RecordEnvMetadata(fakeClient, models.Terraform{WorkspacePrefix: "team-a-"}, "staging", terraform.EnvMetadata{
+				CreatedAt:    createdAt,
+				ExpiresAt:    expiresAt,
+				PipelineName: "some-pipeline",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metadata.ExpiresAt).To(Equal(expiresAt))
+
+			Expect(fakeClient.StatePullArgsForCall(0)).To(Equal("team-a-staging"))
+			Expect(fakeClient.WorkspaceNewIfNotExistsArgsForCall(0)).To(Equal("team-a-staging-meta"))
+			workspace, _ := fakeClient.StatePushArgsForCall(0)
+			Expect(workspace).To(Equal("team-a-staging-meta"))
+			Expect(fakeClient.WorkspaceSelectArgsForCall(0)).To(Equal("team-a-staging"))
+
+			Expect(pushedState["terraform_version"]).To(Equal("1.1.0"))
+			Expect(pushedState["lineage"]).To(MatchRegexp("^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$"))
+			output := pushedState["outputs"].(map[string]interface{})["env_metadata"].(map[string]interface{})
+			Expect(output["value"]).To(MatchJSON(`{
+				"created_at": "2020-01-01T00:00:00Z",
+				"expires_at": "2020-01-04T00:00:00Z",
+				"pipeline_name": "some-pipeline"
+			}`))
+		})
+
+		It("records metadata for an env without any state", func() {
+			fakeClient.StatePullReturns([]byte{}, nil)
+
+			_, err := terraform.RecordEnvMetadata(fakeClient, models.Terraform{}, "staging", terraform.EnvMetadata{
+				CreatedAt: createdAt,
+				ExpiresAt: expiresAt,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeClient.StatePushCallCount()).To(Equal(1))
+			Expect(pushedState).ToNot(HaveKey("terraform_version"))
+		})
+
+		It("keeps the creation details of existing metadata", func() {
+			fakeClient.WorkspaceListReturns([]string{"staging", "staging-meta"}, nil)
+			fakeClient.StatePullStub = func(workspace string) ([]byte, error) {
+				if workspace == "staging-meta" {
+					return envMetadataState(`{"created_at": "2019-12-01T00:00:00Z", "expires_at": "2019-12-02T00:00:00Z", "job_name": "first-job"}`), nil
+				}
+				return []byte(`{"version": 4, "terraform_version": "1.1.0"}`), nil
+			}
+
+			metadata, err := terraform.RecordEnvMetadata(fakeClient, models.Terraform{}, "staging", terraform.EnvMetadata{
+				CreatedAt: createdAt,
+				ExpiresAt: expiresAt,
+				JobName:   "second-job",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metadata).To(Equal(terraform.EnvMetadata{
+				CreatedAt: time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+				ExpiresAt: expiresAt,
+				JobName:   "first-job",
+			}))
+		})
+
+		It("keeps the existing expiry when only labels are given", func() {
+			fakeClient.WorkspaceListReturns([]string{"staging", "staging-meta"}, nil)
+			fakeClient.StatePullStub = func(workspace string) ([]byte, error) {
+				if workspace == "staging-meta" {
+					return envMetadataState(`{"created_at": "2019-12-01T00:00:00Z", "expires_at": "2019-12-02T00:00:00Z", "labels": {"owner": "team-a"}}`), nil
+				}
+				return []byte(`{"version": 4, "terraform_version": "1.1.0"}`), nil
+			}
+
+			metadata, err := terraform.RecordEnvMetadata(fakeClient, models.Terraform{}, "staging", terraform.EnvMetadata{
+				CreatedAt: createdAt,
+				Labels:    map[string]string{"owner": "team-b"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metadata).To(Equal(terraform.EnvMetadata{
+				CreatedAt: time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+				ExpiresAt: time.Date(2019, 12, 2, 0, 0, 0, 0, time.UTC),
+				Labels:    map[string]string{"owner": "team-b"},
+			}))
+		})
+	})
+})
+
+func envMetadataState(metadataJSON string) []byte {
+	state, err := json.Marshal(map[string]interface{}{
+		"version": 4,
+		"outputs": map[string]interface{}{
+			"env_metadata": map[string]interface{}{
+				"value": metadataJSON,
+				"type":  "string",
+			},
+		},
+	})
+	Expect(err).ToNot(HaveOccurred())
+	return state
+}
@@ -74,15 +74,35 @@ func (a *MigratedFromStorageAction) attemptApply() (Result, error) {
 			return Result{}, err
 		}
 	} else {
+		var planMetadata PlanMetadata
 		if a.Model.PlanRun {
-			if err := a.Client.GetPlanFromBackend(a.planNameForEnv()); err != nil {
+			if planMetadata, err = a.Client.GetPlanFromBackend(a.planNameForEnv()); err != nil {
 				return Result{}, err
 			}
+
+			if err := verifyPlanChecksum(a.Model, planMetadata, a.Logger); err != nil {
+				return Result{}, err
+			}
+
+			if needsPlanReview(a.Model) {
+				if err := a.Client.JSONPlan(); err != nil {
+					return Result{}, err
+				}
+				if err := reviewPlan(a.Model, a.Logger); err != nil {
+					return Result{}, err
+				}
+			}
 		}
 
-		if err = a.Client.WorkspaceNewIfNotExists(a.EnvName); err != nil {
+		if err = a.Client.WorkspaceNewIfNotExists(a.workspace()); err != nil {
 			return Result{}, err
 		}
+
+		if a.Model.PlanRun {
+			if err = verifyPlanIsFresh(a.Client, a.Model, a.workspace(), planMetadata, a.Logger); err != nil {
+				return Result{}, err
+			}
+		}
 	}
 
 	// make sure that legacy state file is deleted immediately after new workspace is created
@@ -96,24 +116,35 @@ func (a *MigratedFromStorageAction) attemptApply() (Result, error) {
 		}
 	}
 
-	if err = a.Client.Import(a.EnvName); err != nil {
+	if err = a.Client.Import(a.workspace()); err != nil {
 		return Result{}, err
 	}
 
+	if !a.Model.PlanRun && needsPlanReview(a.Model) {
+		if err = planAndReview(a.Client, a.Model, a.Logger); err != nil {
+			return Result{}, err
+		}
+		defer a.Client.SetModel(a.Model)
+	}
+
 	if err = a.Client.Apply(); err != nil {
 		return Result{}, err
 	}
 
-	stateVersion, err := a.Client.CurrentStateVersion(a.EnvName)
-	if err != nil {
+	if err := a.deletePendingPlans(); err != nil {
 		return Result{}, err
 	}
-	clientOutput, err := a.Client.Output(a.EnvName)
+
+	return a.resultFromState()
+}
+
+func (a *MigratedFromStorageAction) resultFromState() (Result, error) {
+	stateVersion, err := a.Client.CurrentStateVersion(a.workspace())
 	if err != nil {
 		return Result{}, err
 	}
-
-	if err := a.deletePlanWorkspaceIfExists(); err != nil {
+	clientOutput, err := a.Client.Output(a.workspace())
+	if err != nil {
 		return Result{}, err
 	}
 
@@ -178,11 +209,11 @@ func (a *MigratedFromStorageAction) attemptDestroy() (Result, error) {
 		}
 	}
 
-	if err := a.Client.WorkspaceSelect(a.EnvName); err != nil {
+	if err := a.Client.WorkspaceSelect(a.workspace()); err != nil {
 		return Result{}, err
 	}
 
-	if err := a.Client.Import(a.EnvName); err != nil {
+	if err := a.Client.Import(a.workspace()); err != nil {
 		return Result{}, err
 	}
 
@@ -190,11 +221,20 @@ func (a *MigratedFromStorageAction) attemptDestroy() (Result, error) {
 		return Result{}, err
 	}
 
-	if err := a.Client.WorkspaceDelete(a.EnvName); err != nil {
+	if len(a.Model.Targets) > 0 {
+		// a targeted destroy leaves the rest of the env and its workspace in place
+		return a.resultFromState()
+	}
+
+	if err := a.Client.WorkspaceDelete(a.workspace()); err != nil {
+		return Result{}, err
+	}
+
+	if err := a.deletePendingPlans(); err != nil {
 		return Result{}, err
 	}
 
-	if err := a.deletePlanWorkspaceIfExists(); err != nil {
+	if err := deleteEnvMetadata(a.Client, a.workspace()); err != nil {
 		return Result{}, err
 	}
 
@@ -206,6 +246,40 @@ func (a *MigratedFromStorageAction) attemptDestroy() (Result, error) {
 	}, nil
 }
 
+func (a *MigratedFromStorageAction) Refresh() (Result, error) {
+	err := a.setup()
+	if err != nil {
+		return Result{}, err
+	}
+
+	result, err := a.attemptRefresh()
+	if err != nil {
+		a.Logger.Error("Failed To Run Terraform Refresh!")
+		err = fmt.Errorf("Refresh Error: %s", err)
+	}
+
+	if err == nil {
+		a.Logger.Success("Successfully Ran Terraform Refresh!")
+	}
+
+	return result, err
+}
+
+func (a *MigratedFromStorageAction) attemptRefresh() (Result, error) {
+	a.Logger.InfoSection("Terraform Refresh")
+	defer a.Logger.EndSection()
+
+	if err := a.Client.WorkspaceSelect(a.workspace()); err != nil {
+		return Result{}, err
+	}
+
+	if err := a.Client.RefreshOnlyApply(); err != nil {
+		return Result{}, err
+	}
+
+	return a.resultFromState()
+}
+
 func (a *MigratedFromStorageAction) Plan() (Result, error) {
 	err := a.setup()
 	if err != nil {
@@ -263,7 +337,7 @@ func (a *MigratedFromStorageAction) attemptPlan() (Result, error) {
 			return Result{}, err
 		}
 	} else {
-		if err = a.Client.WorkspaceNewIfNotExists(a.EnvName); err != nil {
+		if err = a.Client.WorkspaceNewIfNotExists(a.workspace()); err != nil {
 			return Result{}, err
 		}
 	}
@@ -278,17 +352,25 @@ func (a *MigratedFromStorageAction) attemptPlan() (Result, error) {
 		return Result{}, err
 	}
 
-	if err := a.Client.SavePlanToBackend(a.planNameForEnv()); err != nil {
+	if err = reviewPlan(a.Model, a.Logger); err != nil {
 		return Result{}, err
 	}
 
-	return Result{
-		Output: map[string]map[string]interface{}{},
-		Version: models.Version{
-			EnvName:      a.EnvName,
-			PlanChecksum: planChecksum,
-		},
-	}, nil
+	planMetadata, err := newPlanMetadata(a.Client, a.workspace())
+	if err != nil {
+		return Result{}, err
+	}
+
+	if err := a.Client.SavePlanToBackend(a.planNameForEnv(), planMetadata); err != nil {
+		return Result{}, err
+	}
+
+	planNames, err := pendingPlans(a.Client, a.Model, a.workspace())
+	if err != nil {
+		return Result{}, err
+	}
+
+	return planResult(a.EnvName, planChecksum, a.Model, planNames)
 }
 
 func (a *MigratedFromStorageAction) setup() error {
@@ -312,29 +394,17 @@ func (a *MigratedFromStorageAction) setup() error {
 }
 
 func (a *MigratedFromStorageAction) importExistingStateFileIntoNewWorkspace() error {
-	return a.Client.WorkspaceNewFromExistingStateFile(a.EnvName, a.StateFile.LocalPath)
+	return a.Client.WorkspaceNewFromExistingStateFile(a.workspace(), a.StateFile.LocalPath)
 }
 
-func (a *MigratedFromStorageAction) deletePlanWorkspaceIfExists() error {
-	workspaces, err := a.Client.WorkspaceList()
-
-	if err != nil {
-		return err
-	}
-
-	workspaceExists := false
-	for _, space := range workspaces {
-		if space == a.planNameForEnv() {
-			workspaceExists = true
-		}
-	}
-
-	if workspaceExists {
-		return a.Client.WorkspaceDeleteWithForce(a.planNameForEnv())
-	}
-	return nil
+func (a *MigratedFromStorageAction) deletePendingPlans() error {
+	return deletePendingPlans(a.Client, a.Model, a.workspace())
 }
 
 func (a *MigratedFromStorageAction) planNameForEnv() string {
-	return fmt.Sprintf("%s-plan", a.EnvName)
+	return PlanWorkspaceName(a.workspace(), a.Model.PlanName)
+}
+
+func (a *MigratedFromStorageAction) workspace() string {
+	return a.Model.WorkspaceName(a.EnvName)
 }
@@ -0,0 +1,157 @@
+package terraform
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ljfranklin/terraform-resource/models"
+)
+
+// marks payloads encrypted with `plan_encryption_key`
+const encryptedPlanPrefix = "encrypted:"
+
+// EncodePlanOutput converts a plan payload into the string stored as an
+// output of the plan workspace. The output name is authenticated along with the
+// payload so the binary and JSON plans can't be swapped.
+func EncodePlanOutput(contents []byte, outputName string, model models.Terraform) (string, error) {
+	contents, err := wrapPlan(contents)
+	if err != nil {
+		return "", err
+	}
+
+	if model.PlanEncryptionKey == "" {
+		return base64.StdEncoding.EncodeToString(contents), nil
+	}
+
+	sealed, err := sealPlan(contents, outputName, model)
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedPlanPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecodePlanOutput reverses EncodePlanOutput for the given output of the plan workspace
+func DecodePlanOutput(outputs map[string]map[string]interface{}, outputName string, model models.Terraform) ([]byte, error) {
+	val, ok := outputs[outputName]
+	if !ok {
+		return nil, fmt.Errorf("state has no output for key %s", outputName)
+	}
+	encodedPlan, ok := val["value"].(string)
+	if !ok {
+		return nil, fmt.Errorf("Expected string value for output %s", outputName)
+	}
+
+	encrypted := strings.HasPrefix(encodedPlan, encryptedPlanPrefix)
+	if err := checkPlanEncryption(encrypted, model); err != nil {
+		return nil, err
+	}
+
+	contents, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(encodedPlan, encryptedPlanPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	if encrypted {
+		if contents, err = openPlan(contents, outputName, model); err != nil {
+			return nil, err
+		}
+	}
+
+	if isPlanEnvelope(contents) {
+		return unwrapPlan(contents)
+	}
+	if legacyPlanIsGzipped(outputName) {
+		return gunzipBytes(contents)
+	}
+	return contents, nil
+}
+
+func checkPlanEncryption(encrypted bool, model models.Terraform) error {
+	if encrypted && model.PlanEncryptionKey == "" {
+		return errors.New("Stored plan is encrypted, `plan_encryption_key` must be set to read it")
+	}
+	if !encrypted && model.PlanEncryptionKey != "" {
+		// refuse to apply a plan which may have been swapped by someone with access to the backend
+		return errors.New("Stored plan is not encrypted but `plan_encryption_key` is set, re-run the plan to store an encrypted plan")
+	}
+	return nil
+}
+
+// sealPlan encrypts the contents with `plan_encryption_key`, the name is
+// authenticated along with the contents and the random nonce is prepended
+func sealPlan(contents []byte, name string, model models.Terraform) ([]byte, error) {
+	aead, err := newPlanCipher(model.PlanEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, contents, []byte(name)), nil
+}
+
+func openPlan(sealed []byte, name string, model models.Terraform) ([]byte, error) {
+	aead, err := newPlanCipher(model.PlanEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("Failed to decrypt stored plan: payload is too short")
+	}
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	contents, err := aead.Open(nil, nonce, sealed, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decrypt stored plan, check that `plan_encryption_key` matches the key used to create the plan: %s", err)
+	}
+	return contents, nil
+}
+
+// Before the plan envelope only the JSON and text plans were gzipped:
+// https://github.com/ljfranklin/terraform-resource/issues/115#issuecomment-619525494
+func legacyPlanIsGzipped(outputName string) bool {
+	return outputName != models.PlanContent
+}
+
+func newPlanCipher(encodedKey string) (cipher.AEAD, error) {
+	key, err := models.DecodePlanEncryptionKey(encodedKey)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func gzipBytes(contents []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(contents); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipBytes(contents []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(contents))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return ioutil.ReadAll(zr)
+}
@@ -0,0 +1,165 @@
+package terraform_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+
+	"github.com/ljfranklin/terraform-resource/models"
+	"github.com/ljfranklin/terraform-resource/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PlanEncoding", func() {
+
+	var (
+		encryptedModel models.Terraform
+		planContents   []byte
+	)
+
+	BeforeEach(func() {
+		encryptedModel = models.Terraform{
+			PlanEncryptionKey: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+		}
+		planContents = []byte("fake-plan-with-secrets")
+	})
+
+	outputsFor := func(outputName string, encodedPlan string) map[string]map[string]interface{} {
+		return map[string]map[string]interface{}{
+			outputName: {
+				"value": encodedPlan,
+			},
+		}
+	}
+
+	It("round trips plans without encryption", func() {
+		for _, outputName := range []string{models.PlanContent, models.PlanContentJSON, models.PlanContentText} {
+			encodedPlan, err := terraform.EncodePlanOutput(planContents, outputName, models.Terraform{})
+			Expect(err).ToNot(HaveOccurred())
+
+			decodedPlan, err := terraform.DecodePlanOutput(outputsFor(outputName, encodedPlan), outputName, models.Terraform{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decodedPlan).To(Equal(planContents))
+		}
+	})
+
+	It("round trips plans with encryption", func() {
+		for _, outputName := range []string{models.PlanContent, models.PlanContentJSON, models.PlanContentText} {
+			encodedPlan, err := terraform.EncodePlanOutput(planContents, outputName, encryptedModel)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encodedPlan).To(HavePrefix("encrypted:"))
+
+			decodedPlan, err := terraform.DecodePlanOutput(outputsFor(outputName, encodedPlan), outputName, encryptedModel)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decodedPlan).To(Equal(planContents))
+		}
+	})
+
+	It("returns an error if the key does not match", func() {
+		encodedPlan, err := terraform.EncodePlanOutput(planContents, models.PlanContent, encryptedModel)
+		Expect(err).ToNot(HaveOccurred())
+
+		otherModel := models.Terraform{
+			PlanEncryptionKey: "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=",
+		}
+		_, err = terraform.DecodePlanOutput(outputsFor(models.PlanContent, encodedPlan), models.PlanContent, otherModel)
+		Expect(err).To(MatchError(ContainSubstring("Failed to decrypt stored plan")))
+	})
+
+	It("returns an error if a payload is moved to a different output", func() {
+		encodedPlan, err := terraform.EncodePlanOutput(planContents, models.PlanContentText, encryptedModel)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = terraform.DecodePlanOutput(outputsFor(models.PlanContentJSON, encodedPlan), models.PlanContentJSON, encryptedModel)
+		Expect(err).To(MatchError(ContainSubstring("Failed to decrypt stored plan")))
+	})
+
+	It("refuses unencrypted plans when a key is given", func() {
+		encodedPlan, err := terraform.EncodePlanOutput(planContents, models.PlanContent, models.Terraform{})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = terraform.DecodePlanOutput(outputsFor(models.PlanContent, encodedPlan), models.PlanContent, encryptedModel)
+		Expect(err).To(MatchError(ContainSubstring("Stored plan is not encrypted")))
+	})
+
+	It("returns an error for encrypted plans when no key is given", func() {
+		encodedPlan, err := terraform.EncodePlanOutput(planContents, models.PlanContent, encryptedModel)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = terraform.DecodePlanOutput(outputsFor(models.PlanContent, encodedPlan), models.PlanContent, models.Terraform{})
+		Expect(err).To(MatchError(ContainSubstring("`plan_encryption_key` must be set")))
+	})
+
+	Describe("envelope", func() {
+
+		envelope := func(version byte, compression byte, size uint64, payload []byte) string {
+			var buf bytes.Buffer
+			buf.WriteString("TFRP")
+			buf.WriteByte(version)
+			buf.WriteByte(compression)
+			Expect(binary.Write(&buf, binary.BigEndian, size)).To(Succeed())
+			buf.Write(payload)
+			return base64.StdEncoding.EncodeToString(buf.Bytes())
+		}
+
+		It("compresses binary and JSON plans", func() {
+			largePlan := bytes.Repeat([]byte("aws_instance.web "), 100000)
+
+			for _, outputName := range []string{models.PlanContent, models.PlanContentJSON} {
+				encodedPlan, err := terraform.EncodePlanOutput(largePlan, outputName, models.Terraform{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(len(encodedPlan)).To(BeNumerically("<", len(largePlan)/10))
+
+				rawEnvelope, err := base64.StdEncoding.DecodeString(encodedPlan)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(rawEnvelope[:6])).To(Equal("TFRP\x01\x01"))
+			}
+		})
+
+		It("reads legacy binary plans stored as raw base64", func() {
+			encodedPlan := base64.StdEncoding.EncodeToString(planContents)
+
+			decodedPlan, err := terraform.DecodePlanOutput(outputsFor(models.PlanContent, encodedPlan), models.PlanContent, models.Terraform{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decodedPlan).To(Equal(planContents))
+		})
+
+		It("reads legacy JSON plans stored as gzipped base64", func() {
+			var gzipped bytes.Buffer
+			zw := gzip.NewWriter(&gzipped)
+			_, err := zw.Write(planContents)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(zw.Close()).To(Succeed())
+			encodedPlan := base64.StdEncoding.EncodeToString(gzipped.Bytes())
+
+			decodedPlan, err := terraform.DecodePlanOutput(outputsFor(models.PlanContentJSON, encodedPlan), models.PlanContentJSON, models.Terraform{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decodedPlan).To(Equal(planContents))
+		})
+
+		It("reads uncompressed envelopes", func() {
+			encodedPlan := envelope(1, 0, uint64(len(planContents)), planContents)
+
+			decodedPlan, err := terraform.DecodePlanOutput(outputsFor(models.PlanContent, encodedPlan), models.PlanContent, models.Terraform{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decodedPlan).To(Equal(planContents))
+		})
+
+		It("returns an error for unsupported format versions", func() {
+			encodedPlan := envelope(2, 0, uint64(len(planContents)), planContents)
+
+			_, err := terraform.DecodePlanOutput(outputsFor(models.PlanContent, encodedPlan), models.PlanContent, models.Terraform{})
+			Expect(err).To(MatchError(ContainSubstring("format version 2 which is not supported")))
+		})
+
+		It("returns an error if the plan size does not match the header", func() {
+			encodedPlan := envelope(1, 0, uint64(len(planContents)+1), planContents)
+
+			_, err := terraform.DecodePlanOutput(outputsFor(models.PlanContent, encodedPlan), models.PlanContent, models.Terraform{})
+			Expect(err).To(MatchError(ContainSubstring("Stored plan is corrupt")))
+		})
+	})
+})
@@ -0,0 +1,85 @@
+package terraform
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// Plans are wrapped in a versioned envelope before they are stored:
+//
+//	magic (4 bytes) | format version (1 byte) | compression (1 byte) | plan size (8 bytes) | payload
+//
+// Payloads without the magic bytes were stored by older versions of the
+// resource and are read as the legacy format.
+const (
+	planEnvelopeMagic      = "TFRP"
+	planEnvelopeHeaderSize = len(planEnvelopeMagic) + 1 + 1 + 8
+
+	planEnvelopeVersion byte = 1
+
+	planCompressionNone byte = 0
+	planCompressionGzip byte = 1
+)
+
+func wrapPlan(contents []byte) ([]byte, error) {
+	payload, err := gzipBytes(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope bytes.Buffer
+	envelope.WriteString(planEnvelopeMagic)
+	envelope.WriteByte(planEnvelopeVersion)
+	envelope.WriteByte(planCompressionGzip)
+	if err = binary.Write(&envelope, binary.BigEndian, uint64(len(contents))); err != nil {
+		return nil, err
+	}
+	envelope.Write(payload)
+
+	return envelope.Bytes(), nil
+}
+
+func isPlanEnvelope(contents []byte) bool {
+	return len(contents) >= planEnvelopeHeaderSize && string(contents[:len(planEnvelopeMagic)]) == planEnvelopeMagic
+}
+
+func unwrapPlan(envelope []byte) ([]byte, error) {
+	header := envelope[len(planEnvelopeMagic):planEnvelopeHeaderSize]
+	version, compression := header[0], header[1]
+	size := binary.BigEndian.Uint64(header[2:])
+	payload := envelope[planEnvelopeHeaderSize:]
+
+	if version != planEnvelopeVersion {
+		return nil, fmt.Errorf("Stored plan uses format version %d which is not supported by this version of the resource, upgrade the resource or re-run the plan", version)
+	}
+
+	var payloadReader io.Reader
+	switch compression {
+	case planCompressionNone:
+		payloadReader = bytes.NewReader(payload)
+	case planCompressionGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decompress stored plan: %s", err)
+		}
+		defer zr.Close()
+		payloadReader = zr
+	default:
+		return nil, fmt.Errorf("Stored plan uses unknown compression %d", compression)
+	}
+
+	// read one extra byte to detect payloads larger than the header claims
+	contents, err := ioutil.ReadAll(io.LimitReader(payloadReader, int64(size)+1))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decompress stored plan: %s", err)
+	}
+	if uint64(len(contents)) != size {
+		return nil, fmt.Errorf("Stored plan is corrupt: expected %d bytes but found %d", size, len(contents))
+	}
+
+	return contents, nil
+}
@@ -0,0 +1,201 @@
+package terraform
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ljfranklin/terraform-resource/jsonplan"
+	"github.com/ljfranklin/terraform-resource/logger"
+	"github.com/ljfranklin/terraform-resource/models"
+	"github.com/ljfranklin/terraform-resource/policy"
+)
+
+// verifyPlanChecksum ensures the plan fetched by GetPlanFromBackend is the
+// one that was reviewed, rather than one saved by a later `plan_only` run.
+func verifyPlanChecksum(model models.Terraform, planMetadata PlanMetadata, logger logger.Logger) error {
+	if model.PlanChecksum == "" {
+		if !model.AllowUnverifiedPlan && !planMetadata.CreatedAt.IsZero() {
+			return errors.New("`plan_run` requires `plan_checksum_file` to verify that the stored plan is the reviewed plan. " +
+				"Point it at the `plan_checksum` file of the `get` of the `plan_only` version, or set `allow_unverified_plan: true` to apply the latest stored plan.")
+		}
+		logger.Warn("No `plan_checksum_file` given, applying the latest stored plan without verifying that it is the reviewed plan")
+		return nil
+	}
+
+	checksum, err := planFileChecksum(model.PlanFileLocalPath)
+	if err != nil {
+		return err
+	}
+	if checksum != model.PlanChecksum {
+		return fmt.Errorf("Plan checksum mismatch: expected '%s' from `plan_checksum_file` but the stored plan has '%s'. "+
+			"The plan was likely replaced by another `plan_only` run, re-run the plan job and review it again.", model.PlanChecksum, checksum)
+	}
+
+	return nil
+}
+
+func newPlanMetadata(client Client, envName string) (PlanMetadata, error) {
+	stateVersion, err := client.CurrentStateVersion(envName)
+	if err != nil {
+		return PlanMetadata{}, err
+	}
+
+	return PlanMetadata{
+		StateVersion: stateVersion,
+		CreatedAt:    time.Now(),
+	}, nil
+}
+
+// verifyPlanIsFresh refuses to apply a plan computed against an older state
+// or older than `plan_max_age`. Terraform's own stale plan error is cryptic
+// and doesn't catch every case.
+func verifyPlanIsFresh(client Client, model models.Terraform, envName string, planMetadata PlanMetadata, logger logger.Logger) error {
+	if planMetadata.CreatedAt.IsZero() {
+		logger.Warn("Stored plan was saved by an older version of this resource, skipping stale plan checks")
+		return nil
+	}
+
+	currentVersion, err := client.CurrentStateVersion(envName)
+	if err != nil {
+		return err
+	}
+	if currentVersion != planMetadata.StateVersion {
+		return fmt.Errorf("Plan is stale: it was computed against serial %d with lineage '%s' but env '%s' is now at serial %d with lineage '%s'. "+
+			"Re-run the plan job to compute a new plan.",
+			planMetadata.StateVersion.Serial, planMetadata.StateVersion.Lineage, envName, currentVersion.Serial, currentVersion.Lineage)
+	}
+
+	if model.PlanMaxAge != "" {
+		maxAge, err := time.ParseDuration(model.PlanMaxAge)
+		if err != nil {
+			return fmt.Errorf("Failed to parse `plan_max_age`: %s", err)
+		}
+		if age := time.Since(planMetadata.CreatedAt); age > maxAge {
+			return fmt.Errorf("Plan is stale: it was created at %s, %s ago, which exceeds `plan_max_age` of %s. Re-run the plan job to compute a new plan.",
+				planMetadata.CreatedAt.Format(time.RFC3339), age.Round(time.Second), maxAge)
+		}
+	}
+
+	return nil
+}
+
+func needsPlanReview(model models.Terraform) bool {
+	return model.PolicyFile != "" || model.MaxDestroyCount != nil || model.MaxReplaceCount != nil
+}
+
+// reviewPlan inspects the JSON plan written by Client.JSONPlan
+// and returns an error if it must not be saved or applied.
+func reviewPlan(model models.Terraform, logger logger.Logger) error {
+	if !needsPlanReview(model) {
+		return nil
+	}
+
+	plan, err := jsonplan.Read(model.JSONPlanFileLocalPath)
+	if err != nil {
+		return err
+	}
+
+	if model.PolicyFile != "" {
+		planPolicy, err := policy.ReadFile(model.PolicyFile)
+		if err != nil {
+			return err
+		}
+		if violations := planPolicy.Evaluate(plan); len(violations) > 0 {
+			return policy.ViolationsError{
+				PolicyFile: model.PolicyFile,
+				Violations: violations,
+			}
+		}
+		logger.Info(fmt.Sprintf("Plan complies with policy file '%s'", model.PolicyFile))
+	}
+
+	return checkChangeThresholds(model, plan)
+}
+
+// checkChangeThresholds guards against unattended applies which recreate
+// large parts of an environment. Replaced resources count towards both limits,
+// matching the "to destroy" count in Terraform's plan summary.
+func checkChangeThresholds(model models.Terraform, plan jsonplan.Plan) error {
+	destroyed := []string{}
+	replaced := []string{}
+	for _, change := range plan.ResourceChanges {
+		if !change.IsManaged() {
+			continue
+		}
+		if change.IsReplace() {
+			replaced = append(replaced, change.Address)
+			destroyed = append(destroyed, change.Address)
+		} else if change.IsDelete() {
+			destroyed = append(destroyed, change.Address)
+		}
+	}
+
+	errs := []string{}
+	if model.MaxDestroyCount != nil && len(destroyed) > *model.MaxDestroyCount {
+		errs = append(errs, thresholdError("destroys", destroyed, "max_destroy_count", *model.MaxDestroyCount))
+	}
+	if model.MaxReplaceCount != nil && len(replaced) > *model.MaxReplaceCount {
+		errs = append(errs, thresholdError("replaces", replaced, "max_replace_count", *model.MaxReplaceCount))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
+
+func thresholdError(verb string, addresses []string, param string, max int) string {
+	lines := []string{
+		fmt.Sprintf("Plan %s %d resource(s), exceeding `%s` of %d:", verb, len(addresses), param, max),
+	}
+	for _, address := range addresses {
+		lines = append(lines, fmt.Sprintf("  - %s", address))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// planAndReview is used by the direct apply flow. The client is switched to
+// apply the saved plan so the reviewed plan is exactly what gets applied,
+// callers must restore the original model afterwards.
+func planAndReview(client Client, model models.Terraform, logger logger.Logger) error {
+	if _, err := client.Plan(); err != nil {
+		return err
+	}
+
+	if err := client.JSONPlan(); err != nil {
+		return err
+	}
+
+	if err := reviewPlan(model, logger); err != nil {
+		return err
+	}
+
+	planModel := model
+	planModel.PlanRun = true
+	client.SetModel(planModel)
+
+	return nil
+}
+
+func planResult(envName string, checksum string, model models.Terraform, pendingPlans []string) (Result, error) {
+	plan, err := jsonplan.Read(model.JSONPlanFileLocalPath)
+	if err != nil {
+		return Result{}, err
+	}
+	summary := plan.Summary()
+
+	return Result{
+		Output: map[string]map[string]interface{}{},
+		Version: models.Version{
+			EnvName:      envName,
+			PlanChecksum: checksum,
+			PlanName:     model.PlanName,
+			HasChanges:   strconv.FormatBool(summary.HasChanges()), // Concourse demands version fields are strings
+		},
+		PlanSummary:  &summary,
+		PendingPlans: pendingPlans,
+	}, nil
+}
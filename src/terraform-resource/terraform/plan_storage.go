@@ -0,0 +1,280 @@
+package terraform
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ljfranklin/terraform-resource/models"
+	"github.com/ljfranklin/terraform-resource/storage"
+)
+
+// StoredPlan holds everything saved by `plan_only`
+type StoredPlan struct {
+	Checksum string
+	Plan     []byte
+	JSONPlan []byte
+	TextPlan []byte // nil for plans saved by older versions of the resource
+	Metadata PlanMetadata
+}
+
+// PlanStore saves plans directly to `plan_storage` rather than applying them
+// into a plan workspace with the stateful provider. Each plan is stored under
+// `<plan workspace name>/<checksum>/` and `<plan workspace name>/latest`
+// points at the checksum of the most recent plan.
+type PlanStore struct {
+	StorageDriver storage.Storage
+	Model         models.Terraform
+}
+
+type storedPlanMetadata struct {
+	Checksum     string    `json:"checksum"`
+	StateSerial  int       `json:"state_serial"`
+	StateLineage string    `json:"state_lineage"`
+	CreatedAt    time.Time `json:"created_at"`
+	Encrypted    bool      `json:"encrypted"`
+}
+
+const (
+	latestPlanKey   = "latest"
+	planMetadataKey = "metadata.json"
+)
+
+// maps the stored file names to the output names used to authenticate encrypted plans
+var storedPlanFiles = []struct {
+	fileName   string
+	outputName string
+}{
+	{"plan.tfplan", models.PlanContent},
+	{"plan.json", models.PlanContentJSON},
+	{"plan.txt", models.PlanContentText},
+}
+
+func NewPlanStore(model models.Terraform) PlanStore {
+	return PlanStore{
+		StorageDriver: storage.BuildDriver(model.PlanStorage),
+		Model:         model,
+	}
+}
+
+// ReadStoredPlan returns the plan saved for the given plan workspace name, either
+// from `plan_storage` or from the outputs of the plan workspace. An empty
+// checksum returns the latest plan.
+func ReadStoredPlan(client Client, model models.Terraform, planEnvName string, checksum string) (StoredPlan, error) {
+	if model.UsesPlanStorage() {
+		return NewPlanStore(model).Get(planEnvName, checksum)
+	}
+
+	outputs, err := client.Output(planEnvName)
+	if err != nil {
+		return StoredPlan{}, err
+	}
+
+	plan := StoredPlan{}
+	if plan.Plan, err = DecodePlanOutput(outputs, models.PlanContent, model); err != nil {
+		return StoredPlan{}, err
+	}
+	if plan.JSONPlan, err = DecodePlanOutput(outputs, models.PlanContentJSON, model); err != nil {
+		return StoredPlan{}, err
+	}
+	if _, ok := outputs[models.PlanContentText]; ok {
+		if plan.TextPlan, err = DecodePlanOutput(outputs, models.PlanContentText, model); err != nil {
+			return StoredPlan{}, err
+		}
+	}
+	if plan.Metadata, err = planMetadataFromOutputs(outputs); err != nil {
+		return StoredPlan{}, err
+	}
+	plan.Checksum = planChecksum(plan.Plan)
+
+	return plan, nil
+}
+
+// Save uploads the plan files before updating `latest`, so a concurrent
+// `plan_run` never sees a partially saved plan
+func (s PlanStore) Save(planEnvName string, plan StoredPlan) error {
+	encrypted := s.Model.PlanEncryptionKey != ""
+	planDir := path.Join(planEnvName, plan.Checksum)
+
+	contents := [][]byte{plan.Plan, plan.JSONPlan, plan.TextPlan}
+	for i, file := range storedPlanFiles {
+		fileContents := contents[i]
+		if encrypted {
+			var err error
+			if fileContents, err = sealPlan(fileContents, file.outputName, s.Model); err != nil {
+				return err
+			}
+		}
+		if err := s.upload(path.Join(planDir, file.fileName), fileContents); err != nil {
+			return err
+		}
+	}
+
+	metadata, err := json.Marshal(storedPlanMetadata{
+		Checksum:     plan.Checksum,
+		StateSerial:  plan.Metadata.StateVersion.Serial,
+		StateLineage: plan.Metadata.StateVersion.Lineage,
+		CreatedAt:    plan.Metadata.CreatedAt.UTC(),
+		Encrypted:    encrypted,
+	})
+	if err != nil {
+		return err
+	}
+	if err = s.upload(path.Join(planDir, planMetadataKey), metadata); err != nil {
+		return err
+	}
+
+	return s.upload(path.Join(planEnvName, latestPlanKey), []byte(plan.Checksum))
+}
+
+// Get downloads the plan with the given checksum, or the latest plan if the checksum is empty
+func (s PlanStore) Get(planEnvName string, checksum string) (StoredPlan, error) {
+	if checksum == "" {
+		latest, err := s.download(path.Join(planEnvName, latestPlanKey))
+		if err != nil {
+			return StoredPlan{}, err
+		}
+		if latest == nil {
+			return StoredPlan{}, fmt.Errorf("No plan found for '%s' in `plan_storage`, run `plan_only` first", planEnvName)
+		}
+		checksum = strings.TrimSpace(string(latest))
+	}
+	planDir := path.Join(planEnvName, checksum)
+
+	metadataContents, err := s.download(path.Join(planDir, planMetadataKey))
+	if err != nil {
+		return StoredPlan{}, err
+	}
+	if metadataContents == nil {
+		return StoredPlan{}, fmt.Errorf("Plan '%s' for '%s' no longer exists in `plan_storage`, it was either applied or destroyed", checksum, planEnvName)
+	}
+	metadata := storedPlanMetadata{}
+	if err = json.Unmarshal(metadataContents, &metadata); err != nil {
+		return StoredPlan{}, fmt.Errorf("Failed to parse metadata of plan '%s': %s", checksum, err)
+	}
+	if err = checkPlanEncryption(metadata.Encrypted, s.Model); err != nil {
+		return StoredPlan{}, err
+	}
+
+	contents := make([][]byte, len(storedPlanFiles))
+	for i, file := range storedPlanFiles {
+		fileContents, err := s.download(path.Join(planDir, file.fileName))
+		if err != nil {
+			return StoredPlan{}, err
+		}
+		if fileContents == nil {
+			return StoredPlan{}, fmt.Errorf("Plan '%s' for '%s' is missing %s", checksum, planEnvName, file.fileName)
+		}
+		if metadata.Encrypted {
+			if fileContents, err = openPlan(fileContents, file.outputName, s.Model); err != nil {
+				return StoredPlan{}, err
+			}
+		}
+		contents[i] = fileContents
+	}
+
+	if planChecksum(contents[0]) != metadata.Checksum {
+		return StoredPlan{}, fmt.Errorf("Plan '%s' for '%s' does not match its checksum", checksum, planEnvName)
+	}
+
+	return StoredPlan{
+		Checksum: metadata.Checksum,
+		Plan:     contents[0],
+		JSONPlan: contents[1],
+		TextPlan: contents[2],
+		Metadata: PlanMetadata{
+			StateVersion: StateVersion{
+				Serial:  metadata.StateSerial,
+				Lineage: metadata.StateLineage,
+			},
+			CreatedAt: metadata.CreatedAt,
+		},
+	}, nil
+}
+
+// PlanNames returns the sorted names of all plans saved for the given env
+func (s PlanStore) PlanNames(envName string) ([]string, error) {
+	planEnvNames, err := s.planEnvNames(envName)
+	if err != nil {
+		return nil, err
+	}
+
+	return PendingPlanNames(planEnvNames, envName), nil
+}
+
+// DeleteAll removes every plan saved for the given env
+func (s PlanStore) DeleteAll(envName string) error {
+	keys, err := s.StorageDriver.List(PlanWorkspaceName(envName, DefaultPlanName))
+	if err != nil {
+		return err
+	}
+
+	planNames, err := s.PlanNames(envName)
+	if err != nil {
+		return err
+	}
+	for _, planName := range planNames {
+		planEnvName := PlanWorkspaceName(envName, planName)
+
+		// remove the pointer first so the plan is never read while partially deleted
+		if err = s.StorageDriver.Delete(path.Join(planEnvName, latestPlanKey)); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if strings.HasPrefix(key, planEnvName+"/") {
+				if err = s.StorageDriver.Delete(key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s PlanStore) planEnvNames(envName string) ([]string, error) {
+	keys, err := s.StorageDriver.List(PlanWorkspaceName(envName, DefaultPlanName))
+	if err != nil {
+		return nil, err
+	}
+
+	planEnvNames := []string{}
+	for _, key := range keys {
+		if path.Base(key) == latestPlanKey {
+			planEnvNames = append(planEnvNames, path.Dir(key))
+		}
+	}
+	return planEnvNames, nil
+}
+
+func (s PlanStore) upload(key string, contents []byte) error {
+	if _, err := s.StorageDriver.Upload(key, bytes.NewReader(contents)); err != nil {
+		return fmt.Errorf("Failed to upload '%s' to `plan_storage`: %s", key, err)
+	}
+	return nil
+}
+
+// download returns nil contents if the key does not exist
+func (s PlanStore) download(key string) ([]byte, error) {
+	version, err := s.StorageDriver.Version(key)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to check for '%s' in `plan_storage`: %s", key, err)
+	}
+	if version.IsZero() {
+		return nil, nil
+	}
+
+	var contents bytes.Buffer
+	if _, err = s.StorageDriver.Download(key, &contents); err != nil {
+		return nil, fmt.Errorf("Failed to download '%s' from `plan_storage`: %s", key, err)
+	}
+	return contents.Bytes(), nil
+}
+
+func planChecksum(contents []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(contents))
+}
@@ -0,0 +1,186 @@
+package terraform_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/ljfranklin/terraform-resource/models"
+	"github.com/ljfranklin/terraform-resource/storage"
+	"github.com/ljfranklin/terraform-resource/terraform"
+	"github.com/ljfranklin/terraform-resource/terraform/terraformfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PlanStore", func() {
+
+	var (
+		tmpDir    string
+		model     models.Terraform
+		planStore terraform.PlanStore
+	)
+
+	newPlan := func(contents string) terraform.StoredPlan {
+		return terraform.StoredPlan{
+			Checksum: fmt.Sprintf("%x", sha256.Sum256([]byte(contents))),
+			Plan:     []byte(contents),
+			JSONPlan: []byte(`{"resource_changes": []}`),
+			TextPlan: []byte("No changes."),
+			Metadata: terraform.PlanMetadata{
+				StateVersion: terraform.StateVersion{
+					Serial:  3,
+					Lineage: "some-lineage",
+				},
+				CreatedAt: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir(os.TempDir(), "terraform-resource-plan-store-test")
+		Expect(err).ToNot(HaveOccurred())
+
+		model = models.Terraform{
+			PlanStorage: storage.Model{
+				Driver:    storage.FSDriver,
+				Directory: tmpDir,
+			},
+		}
+		planStore = terraform.NewPlanStore(model)
+	})
+
+	AfterEach(func() {
+		_ = os.RemoveAll(tmpDir)
+	})
+
+	It("returns the latest plan or the plan with the given checksum", func() {
+		firstPlan := newPlan("first-plan")
+		secondPlan := newPlan("second-plan")
+		Expect(planStore.Save("staging-plan", firstPlan)).To(Succeed())
+		Expect(planStore.Save("staging-plan", secondPlan)).To(Succeed())
+
+		Expect(planStore.Get("staging-plan", "")).To(Equal(secondPlan))
+		Expect(planStore.Get("staging-plan", firstPlan.Checksum)).To(Equal(firstPlan))
+
+		Expect(path.Join(tmpDir, "staging-plan", secondPlan.Checksum, "plan.txt")).To(BeARegularFile())
+	})
+
+	It("returns an error if no plan was saved", func() {
+		_, err := planStore.Get("staging-plan", "")
+		Expect(err).To(MatchError(ContainSubstring("No plan found for 'staging-plan'")))
+
+		_, err = planStore.Get("staging-plan", "some-checksum")
+		Expect(err).To(MatchError(ContainSubstring("no longer exists")))
+	})
+
+	It("returns an error if the stored plan does not match its checksum", func() {
+		plan := newPlan("some-plan")
+		Expect(planStore.Save("staging-plan", plan)).To(Succeed())
+
+		planPath := path.Join(tmpDir, "staging-plan", plan.Checksum, "plan.tfplan")
+		Expect(ioutil.WriteFile(planPath, []byte("swapped-plan"), 0600)).To(Succeed())
+
+		_, err := planStore.Get("staging-plan", "")
+		Expect(err).To(MatchError(ContainSubstring("does not match its checksum")))
+	})
+
+	It("encrypts plans with `plan_encryption_key`", func() {
+		model.PlanEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+		encryptedStore := terraform.NewPlanStore(model)
+
+		plan := newPlan("some-plan")
+		Expect(encryptedStore.Save("staging-plan", plan)).To(Succeed())
+		Expect(encryptedStore.Get("staging-plan", "")).To(Equal(plan))
+
+		storedText, err := ioutil.ReadFile(path.Join(tmpDir, "staging-plan", plan.Checksum, "plan.txt"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(storedText)).ToNot(ContainSubstring("No changes."))
+
+		_, err = planStore.Get("staging-plan", "")
+		Expect(err).To(MatchError(ContainSubstring("`plan_encryption_key` must be set")))
+	})
+
+	It("lists and deletes the plans of a single env", func() {
+		Expect(planStore.Save("staging-plan", newPlan("default-plan"))).To(Succeed())
+		Expect(planStore.Save("staging-plan.feature-a", newPlan("feature-plan"))).To(Succeed())
+		Expect(planStore.Save("staging-plan2-plan", newPlan("other-env-plan"))).To(Succeed())
+
+		Expect(planStore.PlanNames("staging")).To(Equal([]string{"default", "feature-a"}))
+		Expect(planStore.PlanNames("staging-plan2")).To(Equal([]string{"default"}))
+
+		Expect(planStore.DeleteAll("staging")).To(Succeed())
+
+		Expect(planStore.PlanNames("staging")).To(BeEmpty())
+		Expect(planStore.PlanNames("staging-plan2")).To(Equal([]string{"default"}))
+		keys, err := planStore.StorageDriver.List("staging-plan")
+		Expect(err).ToNot(HaveOccurred())
+		for _, key := range keys {
+			Expect(key).To(HavePrefix("staging-plan2-plan/"))
+		}
+	})
+
+	Describe("Client#GetPlanFromBackend", func() {
+		BeforeEach(func() {
+			model.PlanFileLocalPath = path.Join(tmpDir, "plan")
+		})
+
+		It("fetches the plan with the given checksum rather than the latest plan", func() {
+			reviewedPlan := newPlan("reviewed-plan")
+			Expect(planStore.Save("staging-plan", reviewedPlan)).To(Succeed())
+			Expect(planStore.Save("staging-plan", newPlan("newer-plan"))).To(Succeed())
+
+			model.PlanChecksum = reviewedPlan.Checksum
+			_, err := terraform.NewClient(model, ioutil.Discard).GetPlanFromBackend("staging-plan")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ioutil.ReadFile(model.PlanFileLocalPath)).To(Equal([]byte("reviewed-plan")))
+		})
+
+		It("fails instead of falling back to the latest plan if the checksum is unknown", func() {
+			Expect(planStore.Save("staging-plan", newPlan("newer-plan"))).To(Succeed())
+
+			model.PlanChecksum = newPlan("reviewed-plan").Checksum
+			_, err := terraform.NewClient(model, ioutil.Discard).GetPlanFromBackend("staging-plan")
+			Expect(err).To(MatchError(ContainSubstring("no longer exists")))
+			Expect(model.PlanFileLocalPath).ToNot(BeAnExistingFile())
+		})
+	})
+
+	Describe("ReadStoredPlan", func() {
+		It("reads the plan from `plan_storage` when configured", func() {
+			plan := newPlan("some-plan")
+			Expect(planStore.Save("staging-plan", plan)).To(Succeed())
+
+			fakeClient := &terraformfakes.FakeClient{}
+			Expect(terraform.ReadStoredPlan(fakeClient, model, "staging-plan", plan.Checksum)).To(Equal(plan))
+			Expect(fakeClient.OutputCallCount()).To(Equal(0))
+		})
+
+		It("reads the plan from the plan workspace outputs otherwise", func() {
+			outputs := map[string]map[string]interface{}{}
+			for outputName, contents := range map[string]string{
+				models.PlanContent:     "some-plan",
+				models.PlanContentJSON: `{"resource_changes": []}`,
+			} {
+				encodedPlan, err := terraform.EncodePlanOutput([]byte(contents), outputName, models.Terraform{})
+				Expect(err).ToNot(HaveOccurred())
+				outputs[outputName] = map[string]interface{}{"value": encodedPlan}
+			}
+			fakeClient := &terraformfakes.FakeClient{}
+			fakeClient.OutputReturns(outputs, nil)
+
+			plan, err := terraform.ReadStoredPlan(fakeClient, models.Terraform{}, "staging-plan", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeClient.OutputArgsForCall(0)).To(Equal("staging-plan"))
+			Expect(plan.Checksum).To(Equal(newPlan("some-plan").Checksum))
+			Expect(string(plan.JSONPlan)).To(Equal(`{"resource_changes": []}`))
+			Expect(plan.TextPlan).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,72 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ljfranklin/terraform-resource/models"
+)
+
+// DefaultPlanName refers to the plan saved without a `plan_name`
+const DefaultPlanName = "default"
+
+// PlanWorkspaceName returns the workspace used to store the named plan for the
+// given env. Named plans use a `.` separator which the resource never generates
+// in env names, so cleaning up one env's plans can't match another env.
+func PlanWorkspaceName(envName string, planName string) string {
+	if planName == "" || planName == DefaultPlanName {
+		return fmt.Sprintf("%s-plan", envName)
+	}
+	return fmt.Sprintf("%s-plan.%s", envName, planName)
+}
+
+// PendingPlanNames returns the sorted names of all plans saved for the given env
+func PendingPlanNames(workspaces []string, envName string) []string {
+	names := []string{}
+	namedPrefix := PlanWorkspaceName(envName, DefaultPlanName) + "."
+	for _, space := range workspaces {
+		if space == PlanWorkspaceName(envName, DefaultPlanName) {
+			names = append(names, DefaultPlanName)
+		} else if strings.HasPrefix(space, namedPrefix) {
+			names = append(names, strings.TrimPrefix(space, namedPrefix))
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// deletePendingPlans removes every plan saved for the env, all of them are
+// stale once the env has been applied or destroyed.
+func deletePendingPlans(client Client, model models.Terraform, envName string) error {
+	if model.UsesPlanStorage() {
+		return NewPlanStore(model).DeleteAll(envName)
+	}
+
+	workspaces, err := client.WorkspaceList()
+	if err != nil {
+		return err
+	}
+
+	for _, planName := range PendingPlanNames(workspaces, envName) {
+		if err = client.WorkspaceDeleteWithForce(PlanWorkspaceName(envName, planName)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pendingPlans returns the names of all plans saved for the env
+func pendingPlans(client Client, model models.Terraform, envName string) ([]string, error) {
+	if model.UsesPlanStorage() {
+		return NewPlanStore(model).PlanNames(envName)
+	}
+
+	workspaces, err := client.WorkspaceList()
+	if err != nil {
+		return nil, err
+	}
+
+	return PendingPlanNames(workspaces, envName), nil
+}
@@ -0,0 +1,47 @@
+package terraform_test
+
+import (
+	"github.com/ljfranklin/terraform-resource/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PlanWorkspaces", func() {
+
+	Describe("#PlanWorkspaceName", func() {
+		It("uses the legacy workspace name for the default plan", func() {
+			Expect(terraform.PlanWorkspaceName("staging", "")).To(Equal("staging-plan"))
+			Expect(terraform.PlanWorkspaceName("staging", "default")).To(Equal("staging-plan"))
+		})
+
+		It("adds the plan name for named plans", func() {
+			Expect(terraform.PlanWorkspaceName("staging", "feature-a")).To(Equal("staging-plan.feature-a"))
+		})
+	})
+
+	Describe("#PendingPlanNames", func() {
+		It("returns the sorted plan names for the given env only", func() {
+			workspaces := []string{
+				"default",
+				"staging",
+				"staging-plan.provider-upgrade",
+				"staging-plan",
+				"staging-plan.feature-a",
+				"staging-two",
+				"staging-two-plan.feature-b",
+				"other-plan",
+			}
+
+			Expect(terraform.PendingPlanNames(workspaces, "staging")).To(Equal([]string{
+				"default",
+				"feature-a",
+				"provider-upgrade",
+			}))
+		})
+
+		It("returns an empty list when there are no plans", func() {
+			Expect(terraform.PendingPlanNames([]string{"staging"}, "staging")).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,122 @@
+package terraform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ljfranklin/terraform-resource/logger"
+	"github.com/ljfranklin/terraform-resource/models"
+)
+
+// Reaper destroys envs whose `ttl` has passed
+type Reaper struct {
+	Client    Client
+	Model     models.Terraform
+	Logger    logger.Logger
+	SourceDir string
+	MaxReap   int
+	DryRun    bool
+}
+
+type ReapResult struct {
+	Expired []string // all expired envs, oldest first
+	Reaped  []string // the envs which were destroyed, empty for a dry run
+}
+
+func (r *Reaper) Reap(now time.Time) (ReapResult, error) {
+	action := Action{
+		Client:    r.Client,
+		Model:     r.Model,
+		Logger:    r.Logger,
+		SourceDir: r.SourceDir,
+	}
+	if err := action.setup(); err != nil {
+		return ReapResult{}, err
+	}
+
+	expired, err := r.expiredEnvs(now)
+	if err != nil {
+		return ReapResult{}, err
+	}
+
+	result := ReapResult{
+		Expired: expired,
+		Reaped:  []string{},
+	}
+	if len(expired) == 0 {
+		r.Logger.Success("No expired environments found")
+		return result, nil
+	}
+	if r.DryRun {
+		r.Logger.Info(fmt.Sprintf("Dry run, would destroy: %s", strings.Join(limitEnvs(expired, r.MaxReap), ", ")))
+		return result, nil
+	}
+
+	for _, envName := range limitEnvs(expired, r.MaxReap) {
+		model := r.Model
+		env := map[string]string{}
+		for key, value := range model.Env {
+			env[key] = value
+		}
+		env["TF_VAR_env_name"] = envName
+		model.Env = env
+		r.Client.SetModel(model)
+
+		action.Model = model
+		action.EnvName = envName
+		if _, err := action.attemptDestroy(); err != nil {
+			r.Logger.Error(fmt.Sprintf("Failed To Reap '%s'!", envName))
+			return result, fmt.Errorf("Failed to reap '%s': %s", envName, err)
+		}
+		result.Reaped = append(result.Reaped, envName)
+	}
+
+	r.Logger.Success(fmt.Sprintf("Successfully Reaped %d Environments!", len(result.Reaped)))
+	return result, nil
+}
+
+func (r *Reaper) expiredEnvs(now time.Time) ([]string, error) {
+	spaces, err := r.Client.WorkspaceList()
+	if err != nil {
+		return nil, err
+	}
+
+	prefixedSpaces := []string{}
+	for _, space := range spaces {
+		if strings.HasPrefix(space, r.Model.WorkspacePrefix) && space != r.Model.WorkspacePrefix {
+			prefixedSpaces = append(prefixedSpaces, space)
+		}
+	}
+
+	expiresAt := map[string]time.Time{}
+	expired := []string{}
+	for _, space := range EnvWorkspaces(prefixedSpaces) {
+		metaWorkspace := MetadataWorkspaceName(space)
+		if !contains(spaces, metaWorkspace) {
+			continue
+		}
+		metadata, err := ReadEnvMetadata(r.Client, space)
+		if err != nil {
+			return nil, err
+		}
+		if metadata.IsExpired(now) {
+			envName := strings.TrimPrefix(space, r.Model.WorkspacePrefix)
+			expiresAt[envName] = metadata.ExpiresAt
+			expired = append(expired, envName)
+		}
+	}
+
+	sort.SliceStable(expired, func(i, j int) bool {
+		return expiresAt[expired[i]].Before(expiresAt[expired[j]])
+	})
+	return expired, nil
+}
+
+func limitEnvs(envNames []string, max int) []string {
+	if max > 0 && len(envNames) > max {
+		return envNames[:max]
+	}
+	return envNames
+}
@@ -0,0 +1,119 @@
+package terraform_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/ljfranklin/terraform-resource/logger"
+	"github.com/ljfranklin/terraform-resource/models"
+	"github.com/ljfranklin/terraform-resource/terraform"
+	"github.com/ljfranklin/terraform-resource/terraform/terraformfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reaper", func() {
+
+	var (
+		fakeClient *terraformfakes.FakeClient
+		reaper     terraform.Reaper
+		now        time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)
+		expiresAt := map[string]time.Time{
+			"team-a-old-meta":    now.Add(-48 * time.Hour),
+			"team-a-older-meta":  now.Add(-72 * time.Hour),
+			"team-a-recent-meta": now.Add(-time.Hour),
+			"team-a-fresh-meta":  now.Add(time.Hour),
+			"old-meta":           now.Add(-96 * time.Hour),
+		}
+
+		fakeClient = &terraformfakes.FakeClient{}
+		fakeClient.WorkspaceListReturns([]string{
+			"default",
+			"old",
+			"old-meta",
+			"team-a-fresh",
+			"team-a-fresh-meta",
+			"team-a-old",
+			"team-a-old-meta",
+			"team-a-older",
+			"team-a-older-meta",
+			"team-a-recent",
+			"team-a-recent-meta",
+			"team-a-unmanaged",
+		}, nil)
+		fakeClient.StatePullStub = func(workspace string) ([]byte, error) {
+			expiry, ok := expiresAt[workspace]
+			if !ok {
+				return nil, fmt.Errorf("unexpected state pull of '%s'", workspace)
+			}
+			return envMetadataState(fmt.Sprintf(`{"created_at": "2020-01-01T00:00:00Z", "expires_at": "%s"}`, expiry.Format(time.RFC3339))), nil
+		}
+
+		reaper = terraform.Reaper{
+			Client: fakeClient,
+			Model: models.Terraform{
+				WorkspacePrefix: "team-a-",
+				Env:             map[string]string{"SOME_VAR": "some-value"},
+			},
+			Logger: logger.Logger{Sink: ioutil.Discard},
+		}
+	})
+
+	It("destroys expired envs within the workspace prefix, oldest first", func() {
+		result, err := reaper.Reap(now)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.Expired).To(Equal([]string{"older", "old", "recent"}))
+		Expect(result.Reaped).To(Equal([]string{"older", "old", "recent"}))
+
+		Expect(fakeClient.DestroyCallCount()).To(Equal(3))
+		Expect(fakeClient.WorkspaceDeleteArgsForCall(0)).To(Equal("team-a-older"))
+		Expect(fakeClient.WorkspaceDeleteArgsForCall(1)).To(Equal("team-a-old"))
+		Expect(fakeClient.WorkspaceDeleteArgsForCall(2)).To(Equal("team-a-recent"))
+		Expect(fakeClient.WorkspaceDeleteWithForceArgsForCall(0)).To(Equal("team-a-older-meta"))
+
+		model := fakeClient.SetModelArgsForCall(0)
+		Expect(model.Env).To(Equal(map[string]string{
+			"SOME_VAR":        "some-value",
+			"TF_VAR_env_name": "older",
+		}))
+	})
+
+	It("destroys at most `max_reap` envs", func() {
+		reaper.MaxReap = 2
+
+		result, err := reaper.Reap(now)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.Expired).To(Equal([]string{"older", "old", "recent"}))
+		Expect(result.Reaped).To(Equal([]string{"older", "old"}))
+		Expect(fakeClient.DestroyCallCount()).To(Equal(2))
+	})
+
+	It("does not destroy anything during a dry run", func() {
+		reaper.DryRun = true
+
+		result, err := reaper.Reap(now)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.Expired).To(Equal([]string{"older", "old", "recent"}))
+		Expect(result.Reaped).To(BeEmpty())
+		Expect(fakeClient.DestroyCallCount()).To(Equal(0))
+		Expect(fakeClient.WorkspaceDeleteCallCount()).To(Equal(0))
+	})
+
+	It("stops at the first env which fails to be destroyed", func() {
+		fakeClient.DestroyReturnsOnCall(1, errors.New("some-error"))
+
+		result, err := reaper.Reap(now)
+		Expect(err).To(MatchError("Failed to reap 'old': some-error"))
+		Expect(result.Reaped).To(Equal([]string{"older"}))
+	})
+})
@@ -41,16 +41,18 @@ type FakeClient struct {
 	destroyReturnsOnCall map[int]struct {
 		result1 error
 	}
-	GetPlanFromBackendStub        func(string) error
+	GetPlanFromBackendStub        func(string) (terraform.PlanMetadata, error)
 	getPlanFromBackendMutex       sync.RWMutex
 	getPlanFromBackendArgsForCall []struct {
 		arg1 string
 	}
 	getPlanFromBackendReturns struct {
-		result1 error
+		result1 terraform.PlanMetadata
+		result2 error
 	}
 	getPlanFromBackendReturnsOnCall map[int]struct {
-		result1 error
+		result1 terraform.PlanMetadata
+		result2 error
 	}
 	ImportStub        func(string) error
 	importMutex       sync.RWMutex
@@ -73,6 +75,17 @@ type FakeClient struct {
 	importWithLegacyStorageReturnsOnCall map[int]struct {
 		result1 error
 	}
+	InitFromModuleStub        func(string) error
+	initFromModuleMutex       sync.RWMutex
+	initFromModuleArgsForCall []struct {
+		arg1 string
+	}
+	initFromModuleReturns struct {
+		result1 error
+	}
+	initFromModuleReturnsOnCall map[int]struct {
+		result1 error
+	}
 	InitWithBackendStub        func() error
 	initWithBackendMutex       sync.RWMutex
 	initWithBackendArgsForCall []struct {
@@ -140,10 +153,34 @@ type FakeClient struct {
 		result1 string
 		result2 error
 	}
-	SavePlanToBackendStub        func(string) error
+	RefreshOnlyApplyStub        func() error
+	refreshOnlyApplyMutex       sync.RWMutex
+	refreshOnlyApplyArgsForCall []struct {
+	}
+	refreshOnlyApplyReturns struct {
+		result1 error
+	}
+	refreshOnlyApplyReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RefreshOnlyPlanStub        func(string) (bool, error)
+	refreshOnlyPlanMutex       sync.RWMutex
+	refreshOnlyPlanArgsForCall []struct {
+		arg1 string
+	}
+	refreshOnlyPlanReturns struct {
+		result1 bool
+		result2 error
+	}
+	refreshOnlyPlanReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
+	SavePlanToBackendStub        func(string, terraform.PlanMetadata) error
 	savePlanToBackendMutex       sync.RWMutex
 	savePlanToBackendArgsForCall []struct {
 		arg1 string
+		arg2 terraform.PlanMetadata
 	}
 	savePlanToBackendReturns struct {
 		result1 error
@@ -169,6 +206,18 @@ type FakeClient struct {
 		result1 []byte
 		result2 error
 	}
+	StatePushStub        func(string, string) error
+	statePushMutex       sync.RWMutex
+	statePushArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	statePushReturns struct {
+		result1 error
+	}
+	statePushReturnsOnCall map[int]struct {
+		result1 error
+	}
 	VersionStub        func() (string, error)
 	versionMutex       sync.RWMutex
 	versionArgsForCall []struct {
@@ -215,6 +264,17 @@ type FakeClient struct {
 		result1 []string
 		result2 error
 	}
+	WorkspaceNewStub        func(string) error
+	workspaceNewMutex       sync.RWMutex
+	workspaceNewArgsForCall []struct {
+		arg1 string
+	}
+	workspaceNewReturns struct {
+		result1 error
+	}
+	workspaceNewReturnsOnCall map[int]struct {
+		result1 error
+	}
 	WorkspaceNewFromExistingStateFileStub        func(string, string) error
 	workspaceNewFromExistingStateFileMutex       sync.RWMutex
 	workspaceNewFromExistingStateFileArgsForCall []struct {
@@ -420,7 +480,7 @@ func (fake *FakeClient) DestroyReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeClient) GetPlanFromBackend(arg1 string) error {
+func (fake *FakeClient) GetPlanFromBackend(arg1 string) (terraform.PlanMetadata, error) {
 	fake.getPlanFromBackendMutex.Lock()
 	ret, specificReturn := fake.getPlanFromBackendReturnsOnCall[len(fake.getPlanFromBackendArgsForCall)]
 	fake.getPlanFromBackendArgsForCall = append(fake.getPlanFromBackendArgsForCall, struct {
@@ -432,10 +492,10 @@ func (fake *FakeClient) GetPlanFromBackend(arg1 string) error {
 		return fake.GetPlanFromBackendStub(arg1)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
 	}
 	fakeReturns := fake.getPlanFromBackendReturns
-	return fakeReturns.result1
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeClient) GetPlanFromBackendCallCount() int {
@@ -444,7 +504,7 @@ func (fake *FakeClient) GetPlanFromBackendCallCount() int {
 	return len(fake.getPlanFromBackendArgsForCall)
 }
 
-func (fake *FakeClient) GetPlanFromBackendCalls(stub func(string) error) {
+func (fake *FakeClient) GetPlanFromBackendCalls(stub func(string) (terraform.PlanMetadata, error)) {
 	fake.getPlanFromBackendMutex.Lock()
 	defer fake.getPlanFromBackendMutex.Unlock()
 	fake.GetPlanFromBackendStub = stub
@@ -457,27 +517,30 @@ func (fake *FakeClient) GetPlanFromBackendArgsForCall(i int) string {
 	return argsForCall.arg1
 }
 
-func (fake *FakeClient) GetPlanFromBackendReturns(result1 error) {
+func (fake *FakeClient) GetPlanFromBackendReturns(result1 terraform.PlanMetadata, result2 error) {
 	fake.getPlanFromBackendMutex.Lock()
 	defer fake.getPlanFromBackendMutex.Unlock()
 	fake.GetPlanFromBackendStub = nil
 	fake.getPlanFromBackendReturns = struct {
-		result1 error
-	}{result1}
+		result1 terraform.PlanMetadata
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeClient) GetPlanFromBackendReturnsOnCall(i int, result1 error) {
+func (fake *FakeClient) GetPlanFromBackendReturnsOnCall(i int, result1 terraform.PlanMetadata, result2 error) {
 	fake.getPlanFromBackendMutex.Lock()
 	defer fake.getPlanFromBackendMutex.Unlock()
 	fake.GetPlanFromBackendStub = nil
 	if fake.getPlanFromBackendReturnsOnCall == nil {
 		fake.getPlanFromBackendReturnsOnCall = make(map[int]struct {
-			result1 error
+			result1 terraform.PlanMetadata
+			result2 error
 		})
 	}
 	fake.getPlanFromBackendReturnsOnCall[i] = struct {
-		result1 error
-	}{result1}
+		result1 terraform.PlanMetadata
+		result2 error
+	}{result1, result2}
 }
 
 func (fake *FakeClient) Import(arg1 string) error {
@@ -592,6 +655,66 @@ func (fake *FakeClient) ImportWithLegacyStorageReturnsOnCall(i int, result1 erro
 	}{result1}
 }
 
+func (fake *FakeClient) InitFromModule(arg1 string) error {
+	fake.initFromModuleMutex.Lock()
+	ret, specificReturn := fake.initFromModuleReturnsOnCall[len(fake.initFromModuleArgsForCall)]
+	fake.initFromModuleArgsForCall = append(fake.initFromModuleArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("InitFromModule", []interface{}{arg1})
+	fake.initFromModuleMutex.Unlock()
+	if fake.InitFromModuleStub != nil {
+		return fake.InitFromModuleStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.initFromModuleReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeClient) InitFromModuleCallCount() int {
+	fake.initFromModuleMutex.RLock()
+	defer fake.initFromModuleMutex.RUnlock()
+	return len(fake.initFromModuleArgsForCall)
+}
+
+func (fake *FakeClient) InitFromModuleCalls(stub func(string) error) {
+	fake.initFromModuleMutex.Lock()
+	defer fake.initFromModuleMutex.Unlock()
+	fake.InitFromModuleStub = stub
+}
+
+func (fake *FakeClient) InitFromModuleArgsForCall(i int) string {
+	fake.initFromModuleMutex.RLock()
+	defer fake.initFromModuleMutex.RUnlock()
+	argsForCall := fake.initFromModuleArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeClient) InitFromModuleReturns(result1 error) {
+	fake.initFromModuleMutex.Lock()
+	defer fake.initFromModuleMutex.Unlock()
+	fake.InitFromModuleStub = nil
+	fake.initFromModuleReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeClient) InitFromModuleReturnsOnCall(i int, result1 error) {
+	fake.initFromModuleMutex.Lock()
+	defer fake.initFromModuleMutex.Unlock()
+	fake.InitFromModuleStub = nil
+	if fake.initFromModuleReturnsOnCall == nil {
+		fake.initFromModuleReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.initFromModuleReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeClient) InitWithBackend() error {
 	fake.initWithBackendMutex.Lock()
 	ret, specificReturn := fake.initWithBackendReturnsOnCall[len(fake.initWithBackendArgsForCall)]
@@ -921,16 +1044,132 @@ func (fake *FakeClient) PlanReturnsOnCall(i int, result1 string, result2 error)
 	}{result1, result2}
 }
 
-func (fake *FakeClient) SavePlanToBackend(arg1 string) error {
+func (fake *FakeClient) RefreshOnlyApply() error {
+	fake.refreshOnlyApplyMutex.Lock()
+	ret, specificReturn := fake.refreshOnlyApplyReturnsOnCall[len(fake.refreshOnlyApplyArgsForCall)]
+	fake.refreshOnlyApplyArgsForCall = append(fake.refreshOnlyApplyArgsForCall, struct {
+	}{})
+	fake.recordInvocation("RefreshOnlyApply", []interface{}{})
+	fake.refreshOnlyApplyMutex.Unlock()
+	if fake.RefreshOnlyApplyStub != nil {
+		return fake.RefreshOnlyApplyStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.refreshOnlyApplyReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeClient) RefreshOnlyApplyCallCount() int {
+	fake.refreshOnlyApplyMutex.RLock()
+	defer fake.refreshOnlyApplyMutex.RUnlock()
+	return len(fake.refreshOnlyApplyArgsForCall)
+}
+
+func (fake *FakeClient) RefreshOnlyApplyCalls(stub func() error) {
+	fake.refreshOnlyApplyMutex.Lock()
+	defer fake.refreshOnlyApplyMutex.Unlock()
+	fake.RefreshOnlyApplyStub = stub
+}
+
+func (fake *FakeClient) RefreshOnlyApplyReturns(result1 error) {
+	fake.refreshOnlyApplyMutex.Lock()
+	defer fake.refreshOnlyApplyMutex.Unlock()
+	fake.RefreshOnlyApplyStub = nil
+	fake.refreshOnlyApplyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeClient) RefreshOnlyApplyReturnsOnCall(i int, result1 error) {
+	fake.refreshOnlyApplyMutex.Lock()
+	defer fake.refreshOnlyApplyMutex.Unlock()
+	fake.RefreshOnlyApplyStub = nil
+	if fake.refreshOnlyApplyReturnsOnCall == nil {
+		fake.refreshOnlyApplyReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.refreshOnlyApplyReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeClient) RefreshOnlyPlan(arg1 string) (bool, error) {
+	fake.refreshOnlyPlanMutex.Lock()
+	ret, specificReturn := fake.refreshOnlyPlanReturnsOnCall[len(fake.refreshOnlyPlanArgsForCall)]
+	fake.refreshOnlyPlanArgsForCall = append(fake.refreshOnlyPlanArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("RefreshOnlyPlan", []interface{}{arg1})
+	fake.refreshOnlyPlanMutex.Unlock()
+	if fake.RefreshOnlyPlanStub != nil {
+		return fake.RefreshOnlyPlanStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.refreshOnlyPlanReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeClient) RefreshOnlyPlanCallCount() int {
+	fake.refreshOnlyPlanMutex.RLock()
+	defer fake.refreshOnlyPlanMutex.RUnlock()
+	return len(fake.refreshOnlyPlanArgsForCall)
+}
+
+func (fake *FakeClient) RefreshOnlyPlanCalls(stub func(string) (bool, error)) {
+	fake.refreshOnlyPlanMutex.Lock()
+	defer fake.refreshOnlyPlanMutex.Unlock()
+	fake.RefreshOnlyPlanStub = stub
+}
+
+func (fake *FakeClient) RefreshOnlyPlanArgsForCall(i int) string {
+	fake.refreshOnlyPlanMutex.RLock()
+	defer fake.refreshOnlyPlanMutex.RUnlock()
+	argsForCall := fake.refreshOnlyPlanArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeClient) RefreshOnlyPlanReturns(result1 bool, result2 error) {
+	fake.refreshOnlyPlanMutex.Lock()
+	defer fake.refreshOnlyPlanMutex.Unlock()
+	fake.RefreshOnlyPlanStub = nil
+	fake.refreshOnlyPlanReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) RefreshOnlyPlanReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.refreshOnlyPlanMutex.Lock()
+	defer fake.refreshOnlyPlanMutex.Unlock()
+	fake.RefreshOnlyPlanStub = nil
+	if fake.refreshOnlyPlanReturnsOnCall == nil {
+		fake.refreshOnlyPlanReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.refreshOnlyPlanReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) SavePlanToBackend(arg1 string, arg2 terraform.PlanMetadata) error {
 	fake.savePlanToBackendMutex.Lock()
 	ret, specificReturn := fake.savePlanToBackendReturnsOnCall[len(fake.savePlanToBackendArgsForCall)]
 	fake.savePlanToBackendArgsForCall = append(fake.savePlanToBackendArgsForCall, struct {
 		arg1 string
-	}{arg1})
-	fake.recordInvocation("SavePlanToBackend", []interface{}{arg1})
+		arg2 terraform.PlanMetadata
+	}{arg1, arg2})
+	fake.recordInvocation("SavePlanToBackend", []interface{}{arg1, arg2})
 	fake.savePlanToBackendMutex.Unlock()
 	if fake.SavePlanToBackendStub != nil {
-		return fake.SavePlanToBackendStub(arg1)
+		return fake.SavePlanToBackendStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
@@ -945,17 +1184,17 @@ func (fake *FakeClient) SavePlanToBackendCallCount() int {
 	return len(fake.savePlanToBackendArgsForCall)
 }
 
-func (fake *FakeClient) SavePlanToBackendCalls(stub func(string) error) {
+func (fake *FakeClient) SavePlanToBackendCalls(stub func(string, terraform.PlanMetadata) error) {
 	fake.savePlanToBackendMutex.Lock()
 	defer fake.savePlanToBackendMutex.Unlock()
 	fake.SavePlanToBackendStub = stub
 }
 
-func (fake *FakeClient) SavePlanToBackendArgsForCall(i int) string {
+func (fake *FakeClient) SavePlanToBackendArgsForCall(i int) (string, terraform.PlanMetadata) {
 	fake.savePlanToBackendMutex.RLock()
 	defer fake.savePlanToBackendMutex.RUnlock()
 	argsForCall := fake.savePlanToBackendArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeClient) SavePlanToBackendReturns(result1 error) {
@@ -1075,6 +1314,67 @@ func (fake *FakeClient) StatePullReturnsOnCall(i int, result1 []byte, result2 er
 	}{result1, result2}
 }
 
+func (fake *FakeClient) StatePush(arg1 string, arg2 string) error {
+	fake.statePushMutex.Lock()
+	ret, specificReturn := fake.statePushReturnsOnCall[len(fake.statePushArgsForCall)]
+	fake.statePushArgsForCall = append(fake.statePushArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	fake.recordInvocation("StatePush", []interface{}{arg1, arg2})
+	fake.statePushMutex.Unlock()
+	if fake.StatePushStub != nil {
+		return fake.StatePushStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.statePushReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeClient) StatePushCallCount() int {
+	fake.statePushMutex.RLock()
+	defer fake.statePushMutex.RUnlock()
+	return len(fake.statePushArgsForCall)
+}
+
+func (fake *FakeClient) StatePushCalls(stub func(string, string) error) {
+	fake.statePushMutex.Lock()
+	defer fake.statePushMutex.Unlock()
+	fake.StatePushStub = stub
+}
+
+func (fake *FakeClient) StatePushArgsForCall(i int) (string, string) {
+	fake.statePushMutex.RLock()
+	defer fake.statePushMutex.RUnlock()
+	argsForCall := fake.statePushArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeClient) StatePushReturns(result1 error) {
+	fake.statePushMutex.Lock()
+	defer fake.statePushMutex.Unlock()
+	fake.StatePushStub = nil
+	fake.statePushReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeClient) StatePushReturnsOnCall(i int, result1 error) {
+	fake.statePushMutex.Lock()
+	defer fake.statePushMutex.Unlock()
+	fake.StatePushStub = nil
+	if fake.statePushReturnsOnCall == nil {
+		fake.statePushReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.statePushReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeClient) Version() (string, error) {
 	fake.versionMutex.Lock()
 	ret, specificReturn := fake.versionReturnsOnCall[len(fake.versionArgsForCall)]
@@ -1305,6 +1605,66 @@ func (fake *FakeClient) WorkspaceListReturnsOnCall(i int, result1 []string, resu
 	}{result1, result2}
 }
 
+func (fake *FakeClient) WorkspaceNew(arg1 string) error {
+	fake.workspaceNewMutex.Lock()
+	ret, specificReturn := fake.workspaceNewReturnsOnCall[len(fake.workspaceNewArgsForCall)]
+	fake.workspaceNewArgsForCall = append(fake.workspaceNewArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("WorkspaceNew", []interface{}{arg1})
+	fake.workspaceNewMutex.Unlock()
+	if fake.WorkspaceNewStub != nil {
+		return fake.WorkspaceNewStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.workspaceNewReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeClient) WorkspaceNewCallCount() int {
+	fake.workspaceNewMutex.RLock()
+	defer fake.workspaceNewMutex.RUnlock()
+	return len(fake.workspaceNewArgsForCall)
+}
+
+func (fake *FakeClient) WorkspaceNewCalls(stub func(string) error) {
+	fake.workspaceNewMutex.Lock()
+	defer fake.workspaceNewMutex.Unlock()
+	fake.WorkspaceNewStub = stub
+}
+
+func (fake *FakeClient) WorkspaceNewArgsForCall(i int) string {
+	fake.workspaceNewMutex.RLock()
+	defer fake.workspaceNewMutex.RUnlock()
+	argsForCall := fake.workspaceNewArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeClient) WorkspaceNewReturns(result1 error) {
+	fake.workspaceNewMutex.Lock()
+	defer fake.workspaceNewMutex.Unlock()
+	fake.WorkspaceNewStub = nil
+	fake.workspaceNewReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeClient) WorkspaceNewReturnsOnCall(i int, result1 error) {
+	fake.workspaceNewMutex.Lock()
+	defer fake.workspaceNewMutex.Unlock()
+	fake.WorkspaceNewStub = nil
+	if fake.workspaceNewReturnsOnCall == nil {
+		fake.workspaceNewReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.workspaceNewReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeClient) WorkspaceNewFromExistingStateFile(arg1 string, arg2 string) error {
 	fake.workspaceNewFromExistingStateFileMutex.Lock()
 	ret, specificReturn := fake.workspaceNewFromExistingStateFileReturnsOnCall[len(fake.workspaceNewFromExistingStateFileArgsForCall)]
@@ -1501,6 +1861,8 @@ func (fake *FakeClient) Invocations() map[string][][]interface{} {
 	defer fake.importMutex.RUnlock()
 	fake.importWithLegacyStorageMutex.RLock()
 	defer fake.importWithLegacyStorageMutex.RUnlock()
+	fake.initFromModuleMutex.RLock()
+	defer fake.initFromModuleMutex.RUnlock()
 	fake.initWithBackendMutex.RLock()
 	defer fake.initWithBackendMutex.RUnlock()
 	fake.initWithoutBackendMutex.RLock()
@@ -1513,12 +1875,18 @@ func (fake *FakeClient) Invocations() map[string][][]interface{} {
 	defer fake.outputWithLegacyStorageMutex.RUnlock()
 	fake.planMutex.RLock()
 	defer fake.planMutex.RUnlock()
+	fake.refreshOnlyApplyMutex.RLock()
+	defer fake.refreshOnlyApplyMutex.RUnlock()
+	fake.refreshOnlyPlanMutex.RLock()
+	defer fake.refreshOnlyPlanMutex.RUnlock()
 	fake.savePlanToBackendMutex.RLock()
 	defer fake.savePlanToBackendMutex.RUnlock()
 	fake.setModelMutex.RLock()
 	defer fake.setModelMutex.RUnlock()
 	fake.statePullMutex.RLock()
 	defer fake.statePullMutex.RUnlock()
+	fake.statePushMutex.RLock()
+	defer fake.statePushMutex.RUnlock()
 	fake.versionMutex.RLock()
 	defer fake.versionMutex.RUnlock()
 	fake.workspaceDeleteMutex.RLock()
@@ -1527,6 +1895,8 @@ func (fake *FakeClient) Invocations() map[string][][]interface{} {
 	defer fake.workspaceDeleteWithForceMutex.RUnlock()
 	fake.workspaceListMutex.RLock()
 	defer fake.workspaceListMutex.RUnlock()
+	fake.workspaceNewMutex.RLock()
+	defer fake.workspaceNewMutex.RUnlock()
 	fake.workspaceNewFromExistingStateFileMutex.RLock()
 	defer fake.workspaceNewFromExistingStateFileMutex.RUnlock()
 	fake.workspaceNewIfNotExistsMutex.RLock()
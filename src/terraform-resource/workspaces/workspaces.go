@@ -1,16 +1,31 @@
 package workspaces
 
 import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ljfranklin/terraform-resource/models"
 	"github.com/ljfranklin/terraform-resource/terraform"
 )
 
 type Workspaces struct {
 	client terraform.Client
+	prefix string
 }
 
 func New(client terraform.Client) *Workspaces {
+	return NewWithPrefix(client, "")
+}
+
+// NewWithPrefix returns Workspaces which look up each env in the workspace
+// named `<prefix><env>`, as configured by `workspace_prefix`
+func NewWithPrefix(client terraform.Client, prefix string) *Workspaces {
 	return &Workspaces{
 		client: client,
+		prefix: prefix,
 	}
 }
 
@@ -20,7 +35,7 @@ func (w Workspaces) LatestVersionForEnv(envName string) (terraform.StateVersion,
 		return terraform.StateVersion{}, err
 	}
 
-	exists, err := w.spaceExists(envName)
+	exists, err := w.spaceExists(w.prefix + envName)
 	if err != nil {
 		return terraform.StateVersion{}, err
 	}
@@ -28,7 +43,216 @@ func (w Workspaces) LatestVersionForEnv(envName string) (terraform.StateVersion,
 		return terraform.StateVersion{}, nil
 	}
 
-	return w.client.CurrentStateVersion(envName)
+	return w.client.CurrentStateVersion(w.prefix + envName)
+}
+
+// LatestVersionsMatching returns the latest state version of every env whose
+// name matches the given pattern, keyed by env name. Plan and metadata workspaces,
+// workspaces outside of the prefix and workspaces without any state are
+// skipped. At most maxWorkers states are fetched concurrently.
+func (w Workspaces) LatestVersionsMatching(pattern *regexp.Regexp, maxWorkers int) (map[string]terraform.StateVersion, error) {
+	err := w.client.InitWithBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	spaces, err := w.client.WorkspaceList()
+	if err != nil {
+		return nil, err
+	}
+
+	envNames := []string{}
+	for _, envName := range w.envNames(spaces) {
+		if pattern.MatchString(envName) {
+			envNames = append(envNames, envName)
+		}
+	}
+
+	versions := map[string]terraform.StateVersion{}
+	lock := sync.Mutex{}
+	err = forEachEnv(envNames, maxWorkers, func(envName string) error {
+		version, err := w.client.CurrentStateVersion(w.prefix + envName)
+		if err != nil {
+			return err
+		}
+		if version != (terraform.StateVersion{}) {
+			lock.Lock()
+			versions[envName] = version
+			lock.Unlock()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// Snapshot records the latest state of each env returned by
+// LatestVersionsMatching, so that `check_env_pattern` can tell which envs
+// changed after a version was emitted
+func Snapshot(versions map[string]terraform.StateVersion) string {
+	entries := []string{}
+	for envName, version := range versions {
+		entries = append(entries, SnapshotEntry(envName, version))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+// SnapshotEntry identifies a state as `<env>:<serial>:<lineage prefix>`,
+// the lineage is shortened to keep the snapshot small
+func SnapshotEntry(envName string, version terraform.StateVersion) string {
+	lineage := version.Lineage
+	if len(lineage) > 8 {
+		lineage = lineage[:8]
+	}
+	return fmt.Sprintf("%s:%d:%s", envName, version.Serial, lineage)
+}
+
+// Env describes an env as written to `envs.json` by `list_envs`
+type Env struct {
+	Name          string            `json:"name"`
+	Serial        int               `json:"serial"`
+	Lineage       string            `json:"lineage"`
+	ResourceCount int               `json:"resource_count"`
+	Labels        map[string]string `json:"labels"`
+	CreatedAt     string            `json:"created_at,omitempty"`
+	ExpiresAt     string            `json:"expires_at,omitempty"`
+}
+
+// List returns every env within the prefix sorted by name, including envs
+// whose workspace holds no state yet. Plan and metadata workspaces are
+// skipped. At most maxWorkers states are fetched concurrently.
+func (w Workspaces) List(maxWorkers int) ([]Env, error) {
+	err := w.client.InitWithBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	spaces, err := w.client.WorkspaceList()
+	if err != nil {
+		return nil, err
+	}
+	existingSpaces := map[string]bool{}
+	for _, space := range spaces {
+		existingSpaces[space] = true
+	}
+
+	envNames := w.envNames(spaces)
+	sort.Strings(envNames)
+
+	envs := make([]Env, len(envNames))
+	indexes := map[string]int{}
+	for i, envName := range envNames {
+		indexes[envName] = i
+	}
+	err = forEachEnv(envNames, maxWorkers, func(envName string) error {
+		workspace := w.prefix + envName
+		rawState, err := w.client.StatePull(workspace)
+		if err != nil {
+			return err
+		}
+		version, err := terraform.ParseStateVersion(rawState)
+		if err != nil {
+			return err
+		}
+		resourceCount, err := terraform.StateResourceCount(rawState)
+		if err != nil {
+			return err
+		}
+
+		env := Env{
+			Name:          envName,
+			Serial:        version.Serial,
+			Lineage:       version.Lineage,
+			ResourceCount: resourceCount,
+			Labels:        map[string]string{},
+		}
+		if existingSpaces[terraform.MetadataWorkspaceName(workspace)] {
+			metadata, err := terraform.ReadEnvMetadata(w.client, workspace)
+			if err != nil {
+				return err
+			}
+			if metadata.Labels != nil {
+				env.Labels = metadata.Labels
+			}
+			if !metadata.CreatedAt.IsZero() {
+				env.CreatedAt = metadata.CreatedAt.Format(models.TimeFormat)
+			}
+			if !metadata.ExpiresAt.IsZero() {
+				env.ExpiresAt = metadata.ExpiresAt.Format(models.TimeFormat)
+			}
+		}
+
+		// each worker writes to its own index
+		envs[indexes[envName]] = env
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return envs, nil
+}
+
+// envNames returns the unprefixed names of the env workspaces within the prefix
+func (w Workspaces) envNames(spaces []string) []string {
+	prefixedSpaces := []string{}
+	for _, space := range spaces {
+		if strings.HasPrefix(space, w.prefix) && space != w.prefix {
+			prefixedSpaces = append(prefixedSpaces, space)
+		}
+	}
+
+	envNames := []string{}
+	for _, space := range terraform.EnvWorkspaces(prefixedSpaces) {
+		envNames = append(envNames, strings.TrimPrefix(space, w.prefix))
+	}
+	return envNames
+}
+
+// forEachEnv calls fetch for each env using at most maxWorkers goroutines,
+// the first error is returned once all envs have been processed
+func forEachEnv(envNames []string, maxWorkers int, fetch func(envName string) error) error {
+	if maxWorkers < 1 {
+		maxWorkers = 1
+	}
+
+	type result struct {
+		envName string
+		err     error
+	}
+
+	jobs := make(chan string)
+	results := make(chan result)
+	wg := sync.WaitGroup{}
+	for i := 0; i < maxWorkers && i < len(envNames); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for envName := range jobs {
+				results <- result{envName: envName, err: fetch(envName)}
+			}
+		}()
+	}
+	go func() {
+		for _, envName := range envNames {
+			jobs <- envName
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	var firstErr error
+	for res := range results {
+		if res.err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("Failed to fetch state of '%s': %s", res.envName, res.err)
+		}
+	}
+	return firstErr
 }
 
 func (w Workspaces) spaceExists(envName string) (bool, error) {
@@ -2,6 +2,9 @@ package workspaces_test
 
 import (
 	"errors"
+	"regexp"
+	"sync"
+
 	"github.com/ljfranklin/terraform-resource/terraform"
 	"github.com/ljfranklin/terraform-resource/terraform/terraformfakes"
 	"github.com/ljfranklin/terraform-resource/workspaces"
@@ -53,6 +56,35 @@ var _ = Describe("Workspaces", func() {
 			})
 		})
 
+		Context("when a workspace prefix is given", func() {
+			BeforeEach(func() {
+				fakeTerraform = &terraformfakes.FakeClient{}
+				fakeTerraform.WorkspaceListReturns([]string{"some-env", "team-a-some-env"}, nil)
+				fakeTerraform.CurrentStateVersionReturns(terraform.StateVersion{
+					Serial:  7,
+					Lineage: "aaaaa",
+				}, nil)
+			})
+
+			It("looks up the prefixed workspace", func() {
+				spaces := workspaces.NewWithPrefix(fakeTerraform, "team-a-")
+
+				version, err := spaces.LatestVersionForEnv("some-env")
+				Expect(err).To(BeNil())
+				Expect(version.Serial).To(Equal(7))
+				Expect(fakeTerraform.CurrentStateVersionArgsForCall(0)).To(Equal("team-a-some-env"))
+			})
+
+			It("returns an empty Version if only the unprefixed workspace exists", func() {
+				fakeTerraform.WorkspaceListReturns([]string{"some-env"}, nil)
+				spaces := workspaces.NewWithPrefix(fakeTerraform, "team-a-")
+
+				version, err := spaces.LatestVersionForEnv("some-env")
+				Expect(err).To(BeNil())
+				Expect(version).To(Equal(terraform.StateVersion{}))
+			})
+		})
+
 		Context("when initializing fails", func() {
 			BeforeEach(func() {
 				fakeTerraform = &terraformfakes.FakeClient{}
@@ -96,4 +128,218 @@ var _ = Describe("Workspaces", func() {
 			})
 		})
 	})
+
+	Describe("#LatestVersionsMatching", func() {
+		var fakeTerraform *terraformfakes.FakeClient
+
+		BeforeEach(func() {
+			fakeTerraform = &terraformfakes.FakeClient{}
+			fakeTerraform.WorkspaceListReturns([]string{
+				"default",
+				"pr-1",
+				"pr-1-plan",
+				"pr-2",
+				"pr-2-plan.some-plan",
+				"pr-3",
+				"staging",
+			}, nil)
+			fakeTerraform.CurrentStateVersionStub = func(envName string) (terraform.StateVersion, error) {
+				switch envName {
+				case "pr-1":
+					return terraform.StateVersion{Serial: 1, Lineage: "aaaaa"}, nil
+				case "pr-2":
+					return terraform.StateVersion{Serial: 2, Lineage: "bbbbb"}, nil
+				}
+				return terraform.StateVersion{}, nil
+			}
+		})
+
+		It("returns the latest version of each matching env with state", func() {
+			spaces := workspaces.New(fakeTerraform)
+
+			versions, err := spaces.LatestVersionsMatching(regexp.MustCompile("^pr-"), 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(versions).To(Equal(map[string]terraform.StateVersion{
+				"pr-1": {Serial: 1, Lineage: "aaaaa"},
+				"pr-2": {Serial: 2, Lineage: "bbbbb"},
+			}))
+
+			Expect(fakeTerraform.WorkspaceListCallCount()).To(Equal(1))
+			Expect(fakeTerraform.CurrentStateVersionCallCount()).To(Equal(3))
+			for i := 0; i < fakeTerraform.CurrentStateVersionCallCount(); i++ {
+				Expect(fakeTerraform.CurrentStateVersionArgsForCall(i)).To(MatchRegexp("^pr-[0-9]$"))
+			}
+		})
+
+		It("only matches envs within the workspace prefix", func() {
+			fakeTerraform.WorkspaceListReturns([]string{
+				"pr-1",
+				"team-a-pr-1",
+				"team-a-pr-1-plan",
+				"team-a-pr-2",
+			}, nil)
+			fakeTerraform.CurrentStateVersionStub = func(space string) (terraform.StateVersion, error) {
+				return terraform.StateVersion{Serial: 1, Lineage: space}, nil
+			}
+
+			spaces := workspaces.NewWithPrefix(fakeTerraform, "team-a-")
+
+			versions, err := spaces.LatestVersionsMatching(regexp.MustCompile("^pr-"), 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(versions).To(Equal(map[string]terraform.StateVersion{
+				"pr-1": {Serial: 1, Lineage: "team-a-pr-1"},
+				"pr-2": {Serial: 1, Lineage: "team-a-pr-2"},
+			}))
+		})
+
+		It("fetches no more than maxWorkers states at once", func() {
+			fakeTerraform.WorkspaceListReturns([]string{"pr-1", "pr-2", "pr-3", "pr-4", "pr-5"}, nil)
+
+			var mutex sync.Mutex
+			inFlight := 0
+			maxInFlight := 0
+			fakeTerraform.CurrentStateVersionStub = func(envName string) (terraform.StateVersion, error) {
+				mutex.Lock()
+				inFlight++
+				if inFlight > maxInFlight {
+					maxInFlight = inFlight
+				}
+				mutex.Unlock()
+
+				defer func() {
+					mutex.Lock()
+					inFlight--
+					mutex.Unlock()
+				}()
+				return terraform.StateVersion{Serial: 1, Lineage: envName}, nil
+			}
+
+			spaces := workspaces.New(fakeTerraform)
+
+			versions, err := spaces.LatestVersionsMatching(regexp.MustCompile("^pr-"), 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(versions).To(HaveLen(5))
+			Expect(maxInFlight).To(BeNumerically("<=", 2))
+		})
+
+		It("returns an error if fetching any state fails", func() {
+			fakeTerraform.CurrentStateVersionStub = func(envName string) (terraform.StateVersion, error) {
+				if envName == "pr-3" {
+					return terraform.StateVersion{}, errors.New("some-error")
+				}
+				return terraform.StateVersion{Serial: 1, Lineage: "aaaaa"}, nil
+			}
+
+			spaces := workspaces.New(fakeTerraform)
+
+			_, err := spaces.LatestVersionsMatching(regexp.MustCompile("^pr-"), 2)
+			Expect(err).To(MatchError("Failed to fetch state of 'pr-3': some-error"))
+		})
+
+		It("returns the error if listing workspaces fails", func() {
+			fakeTerraform.WorkspaceListReturns(nil, errors.New("some-error"))
+
+			spaces := workspaces.New(fakeTerraform)
+
+			_, err := spaces.LatestVersionsMatching(regexp.MustCompile("^pr-"), 2)
+			Expect(err).To(MatchError("some-error"))
+		})
+	})
+
+	Describe("#Snapshot", func() {
+		It("lists each env sorted by name with its serial and shortened lineage", func() {
+			snapshot := workspaces.Snapshot(map[string]terraform.StateVersion{
+				"pr-2": {Serial: 3, Lineage: "f4b8c2a0-1111-4222-8333-444455556666"},
+				"pr-1": {Serial: 12, Lineage: "0a1b2c3d-1111-4222-8333-444455556666"},
+			})
+
+			Expect(snapshot).To(Equal("pr-1:12:0a1b2c3d,pr-2:3:f4b8c2a0"))
+		})
+	})
+
+	Describe("#List", func() {
+		var fakeTerraform *terraformfakes.FakeClient
+
+		BeforeEach(func() {
+			fakeTerraform = &terraformfakes.FakeClient{}
+			fakeTerraform.WorkspaceListReturns([]string{
+				"default",
+				"team-a-staging",
+				"team-a-staging-meta",
+				"team-a-staging-plan",
+				"team-a-reserved",
+				"team-a-pr-1",
+			}, nil)
+			fakeTerraform.StatePullStub = func(space string) ([]byte, error) {
+				switch space {
+				case "team-a-staging":
+					return []byte(`{
+						"version": 4,
+						"serial": 7,
+						"lineage": "aaaaa",
+						"resources": [
+							{"mode": "managed", "type": "aws_instance", "name": "web", "instances": [{}, {}]},
+							{"mode": "managed", "type": "aws_eip", "name": "web", "instances": [{}]},
+							{"mode": "data", "type": "aws_ami", "name": "ubuntu", "instances": [{}]}
+						]
+					}`), nil
+				case "team-a-staging-meta":
+					return []byte(`{
+						"version": 4,
+						"outputs": {
+							"env_metadata": {
+								"value": "{\"created_at\": \"2020-01-01T00:00:00Z\", \"expires_at\": \"0001-01-01T00:00:00Z\", \"labels\": {\"owner\": \"team-a\"}}",
+								"type": "string"
+							}
+						}
+					}`), nil
+				case "team-a-pr-1":
+					return []byte(`{"version": 4, "serial": 2, "lineage": "bbbbb", "resources": []}`), nil
+				}
+				return []byte{}, nil
+			}
+		})
+
+		It("returns every env within the prefix sorted by name", func() {
+			spaces := workspaces.NewWithPrefix(fakeTerraform, "team-a-")
+
+			envs, err := spaces.List(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(envs).To(Equal([]workspaces.Env{
+				{
+					Name:          "pr-1",
+					Serial:        2,
+					Lineage:       "bbbbb",
+					ResourceCount: 0,
+					Labels:        map[string]string{},
+				},
+				{
+					Name:          "reserved",
+					ResourceCount: 0,
+					Labels:        map[string]string{},
+				},
+				{
+					Name:          "staging",
+					Serial:        7,
+					Lineage:       "aaaaa",
+					ResourceCount: 3,
+					Labels:        map[string]string{"owner": "team-a"},
+					CreatedAt:     "2020-01-01T00:00:00Z",
+				},
+			}))
+
+			Expect(fakeTerraform.WorkspaceListCallCount()).To(Equal(1))
+		})
+
+		It("returns an error if fetching any state fails", func() {
+			fakeTerraform.StatePullReturns(nil, errors.New("some-error"))
+			fakeTerraform.StatePullStub = nil
+			fakeTerraform.WorkspaceListReturns([]string{"staging"}, nil)
+
+			spaces := workspaces.New(fakeTerraform)
+
+			_, err := spaces.List(2)
+			Expect(err).To(MatchError("Failed to fetch state of 'staging': some-error"))
+		})
+	})
 })